@@ -0,0 +1,66 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/koozz/gh-semver/internal/semver"
+)
+
+// attestation is the provenance statement of a calculated version
+type attestation struct {
+	Commit   string              `json:"commit"`
+	Version  string              `json:"version"`
+	Previous string              `json:"previous,omitempty"`
+	Bump     string              `json:"bump"`
+	Reasons  []semver.BumpReason `json:"reasons"`
+}
+
+func newAttestation(explain *semver.Explanation, tagVersion string) attestation {
+	return attestation{
+		Commit:   explain.Commit,
+		Version:  tagVersion,
+		Previous: explain.Previous,
+		Bump:     explain.Bump,
+		Reasons:  explain.Reasons,
+	}
+}
+
+// writeAttestation writes the attestation as JSON to path and an armored
+// detached signature of it to path.asc
+func writeAttestation(path string, att attestation, signer *openpgp.Entity) error {
+	data, err := json.MarshalIndent(att, "", "  ")
+	if err != nil {
+		return fmt.Errorf("couldn't marshal attestation: %w", err)
+	}
+	data = append(data, '\n')
+
+	var signature bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&signature, signer, bytes.NewReader(data), nil); err != nil {
+		return fmt.Errorf("couldn't sign attestation: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("couldn't write attestation: %w", err)
+	}
+	if err := os.WriteFile(path+".asc", signature.Bytes(), 0644); err != nil {
+		return fmt.Errorf("couldn't write attestation signature: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/koozz/gh-semver/internal/semver"
+)
+
+func TestWriteAttestation(t *testing.T) {
+	repo, first := twoCommits(t)
+	if _, err := repo.CreateTag("v1.0.0", first, nil); err != nil {
+		t.Fatal(err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conventionalCommits := semver.NewConventionalCommits(repo, semver.Options{MainBranch: "master"})
+	version, err := conventionalCommits.SemVer()
+	if err != nil {
+		t.Fatalf("SemVer() error: %v", err)
+	}
+	tagVersion := version.PrintTag(false)
+
+	signer, err := openpgp.NewEntity("Test", "", "test@example.com", nil)
+	if err != nil {
+		t.Fatalf("couldn't generate signing key: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "attestation.json")
+	if err := writeAttestation(path, newAttestation(conventionalCommits.Explain(), tagVersion), signer); err != nil {
+		t.Fatalf("writeAttestation() error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got attestation
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("couldn't decode attestation: %v", err)
+	}
+	if got.Commit != head.Hash().String() {
+		t.Errorf("attestation commit = %s, want %s", got.Commit, head.Hash())
+	}
+	if got.Version != tagVersion {
+		t.Errorf("attestation version = %s, want %s", got.Version, tagVersion)
+	}
+	if got.Version != "v1.0.1" || got.Previous != "v1.0.0" {
+		t.Errorf("attestation = %s from %s, want v1.0.1 from v1.0.0", got.Version, got.Previous)
+	}
+	if got.Bump != "patch" || len(got.Reasons) != 1 || got.Reasons[0].Subject != "fix: second" {
+		t.Errorf("attestation bump = %s %v, want the patch of fix: second", got.Bump, got.Reasons)
+	}
+
+	signature, err := os.ReadFile(path + ".asc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring := openpgp.EntityList{signer}
+	if _, err := openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(data), bytes.NewReader(signature), nil); err != nil {
+		t.Errorf("attestation signature doesn't verify: %v", err)
+	}
+}
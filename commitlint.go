@@ -0,0 +1,121 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// commitlint config files, in order of precedence
+var commitlintFiles = []string{
+	".commitlintrc",
+	".commitlintrc.json",
+	".commitlintrc.yaml",
+	".commitlintrc.yml",
+	"commitlint.config.js",
+	"commitlint.config.cjs",
+	"commitlint.config.mjs",
+	"commitlint.config.ts",
+	"package.json",
+}
+
+var (
+	// matches 'type-enum': [2, 'always', [...]] in javascript configs
+	commitlintTypeEnum = regexp.MustCompile(`['"]?type-enum['"]?\s*:\s*\[\s*\d+\s*,\s*['"]always['"]\s*,\s*\[([^\]]*)\]`)
+	commitlintQuoted   = regexp.MustCompile(`['"]([^'"]+)['"]`)
+)
+
+type commitlintConfig struct {
+	Rules map[string][]interface{} `json:"rules" yaml:"rules"`
+}
+
+// loadCommitlintTypes reads the allowed commit types of the type-enum rule
+// from the commitlint config in the root of the repository. This is best
+// effort, javascript configs are scanned rather than evaluated.
+func loadCommitlintTypes(gitRoot string) ([]string, error) {
+	for _, name := range commitlintFiles {
+		data, err := os.ReadFile(filepath.Join(gitRoot, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read %s: %w", name, err)
+		}
+
+		var types []string
+		switch filepath.Ext(name) {
+		case ".js", ".cjs", ".mjs", ".ts":
+			types = scanTypeEnum(data)
+		case ".json":
+			if name == "package.json" {
+				var pkg struct {
+					Commitlint commitlintConfig `json:"commitlint"`
+				}
+				if err := json.Unmarshal(data, &pkg); err != nil {
+					return nil, fmt.Errorf("couldn't parse %s: %w", name, err)
+				}
+				types = typeEnum(pkg.Commitlint)
+				break
+			}
+			fallthrough
+		default:
+			// yaml is a superset of json
+			var config commitlintConfig
+			if err := yaml.Unmarshal(data, &config); err != nil {
+				return nil, fmt.Errorf("couldn't parse %s: %w", name, err)
+			}
+			types = typeEnum(config)
+		}
+		if len(types) > 0 {
+			return types, nil
+		}
+	}
+	return nil, fmt.Errorf("no commitlint type-enum rule found")
+}
+
+func typeEnum(config commitlintConfig) []string {
+	rule := config.Rules["type-enum"]
+	if len(rule) < 3 || rule[1] != "always" {
+		return nil
+	}
+	values, ok := rule[2].([]interface{})
+	if !ok {
+		return nil
+	}
+	types := []string{}
+	for _, value := range values {
+		if commitType, ok := value.(string); ok {
+			types = append(types, commitType)
+		}
+	}
+	return types
+}
+
+func scanTypeEnum(data []byte) []string {
+	matches := commitlintTypeEnum.FindSubmatch(data)
+	if matches == nil {
+		return nil
+	}
+	types := []string{}
+	for _, quoted := range commitlintQuoted.FindAllSubmatch(matches[1], -1) {
+		types = append(types, string(quoted[1]))
+	}
+	return types
+}
@@ -0,0 +1,95 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadCommitlintTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "javascript",
+			file: "commitlint.config.js",
+			content: `module.exports = {
+  extends: ['@commitlint/config-conventional'],
+  rules: {
+    'type-enum': [2, 'always', ['feat', 'fix', 'perf', "docs"]],
+  },
+};
+`,
+			want: []string{"feat", "fix", "perf", "docs"},
+		},
+		{
+			name:    "json",
+			file:    ".commitlintrc.json",
+			content: `{"rules": {"type-enum": [2, "always", ["feat", "fix"]]}}`,
+			want:    []string{"feat", "fix"},
+		},
+		{
+			name:    "yaml",
+			file:    ".commitlintrc.yml",
+			content: "rules:\n  type-enum:\n    - 2\n    - always\n    - [feat, fix, chore]\n",
+			want:    []string{"feat", "fix", "chore"},
+		},
+		{
+			name:    "package.json",
+			file:    "package.json",
+			content: `{"name": "app", "commitlint": {"rules": {"type-enum": [2, "always", ["feat", "fix"]]}}}`,
+			want:    []string{"feat", "fix"},
+		},
+		{
+			name:    "never",
+			file:    ".commitlintrc.json",
+			content: `{"rules": {"type-enum": [2, "never", ["wip"]]}}`,
+			wantErr: true,
+		},
+		{
+			name:    "no config",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			if test.file != "" {
+				if err := os.WriteFile(filepath.Join(root, test.file), []byte(test.content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := loadCommitlintTypes(root)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("loadCommitlintTypes() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadCommitlintTypes() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("loadCommitlintTypes() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultConfigFile = ".gh-semver.yml"
+
+// applyConfig sets the flags that weren't given on the commandline from the
+// config file. The config file is resolved relative to the root of the
+// repository, so it is found from any subdirectory.
+func applyConfig(flags *flag.FlagSet, gitRoot, path string) error {
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigFile
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(gitRoot, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !explicit {
+			return nil
+		}
+		return fmt.Errorf("couldn't read config: %w", err)
+	}
+	config := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("couldn't parse config %s: %w", path, err)
+	}
+
+	given := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	names := make([]string, 0, len(config))
+	for name := range config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if flags.Lookup(name) == nil {
+			return fmt.Errorf("unknown option '%s' in config %s", name, path)
+		}
+		if given[name] {
+			continue
+		}
+		values, ok := config[name].([]interface{})
+		if !ok {
+			values = []interface{}{config[name]}
+		}
+		for _, value := range values {
+			if err := flags.Set(name, fmt.Sprint(value)); err != nil {
+				return fmt.Errorf("invalid value for '%s' in config %s: %w", name, path, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyConfigTagType(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "default from the config", want: lightweightTag},
+		{name: "commandline wins", args: []string{"-tag-type", annotatedTag}, want: annotatedTag},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			if err := os.WriteFile(filepath.Join(root, defaultConfigFile), []byte("tag-type: lightweight\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			flags := flag.NewFlagSet("test", flag.ContinueOnError)
+			tagType := flags.String("tag-type", annotatedTag, "")
+			if err := flags.Parse(test.args); err != nil {
+				t.Fatal(err)
+			}
+			if err := applyConfig(flags, root, ""); err != nil {
+				t.Fatalf("applyConfig() error: %v", err)
+			}
+			if *tagType != test.want {
+				t.Errorf("applyConfig() tag-type = %s, want %s", *tagType, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+)
+
+// prefixFromGoMod derives the tag prefix and filter path of the Go module in
+// dir, e.g. module example.com/repo/api in api/ results in prefix 'api/' and
+// filter path 'api/', as Go expects the tags of submodules like api/v1.2.3.
+// A relative dir is relative to the repository root, like the filter path.
+func prefixFromGoMod(gitRoot, dir string) (string, string, error) {
+	if gitRoot == "" {
+		return "", "", fmt.Errorf("couldn't find go.mod in %s: the repository has no worktree", dir)
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(gitRoot, dir)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return "", "", fmt.Errorf("couldn't read go.mod: %w", err)
+	}
+	modulePath := modfile.ModulePath(data)
+	if modulePath == "" {
+		return "", "", fmt.Errorf("no module path found in %s", filepath.Join(dir, "go.mod"))
+	}
+
+	absRoot, err := filepath.Abs(gitRoot)
+	if err != nil {
+		return "", "", fmt.Errorf("couldn't resolve %s: %w", gitRoot, err)
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", fmt.Errorf("couldn't resolve %s: %w", dir, err)
+	}
+	relDir, err := filepath.Rel(absRoot, absDir)
+	if err != nil || strings.HasPrefix(relDir, "..") {
+		return "", "", fmt.Errorf("%s is not within the repository", dir)
+	}
+	relDir = filepath.ToSlash(relDir)
+
+	// the root module is versioned without prefix
+	if relDir == "." {
+		return "", "", nil
+	}
+
+	// strip a major version suffix like /v2
+	if pathPrefix, _, ok := module.SplitPathVersion(modulePath); ok {
+		modulePath = pathPrefix
+	}
+	prefix := path.Base(modulePath)
+	if strings.HasSuffix(modulePath, "/"+relDir) {
+		prefix = relDir
+	}
+	return prefix + "/", relDir + "/", nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPrefixFromGoMod(t *testing.T) {
+	tests := []struct {
+		name       string
+		dir        string
+		modulePath string
+		prefix     string
+		filterPath string
+	}{
+		{name: "root module", dir: ".", modulePath: "example.com/repo", prefix: "", filterPath: ""},
+		{name: "submodule", dir: "api", modulePath: "example.com/repo/api", prefix: "api/", filterPath: "api/"},
+		{name: "major version", dir: "api", modulePath: "example.com/repo/api/v2", prefix: "api/", filterPath: "api/"},
+		{name: "nested submodule", dir: "services/api", modulePath: "example.com/repo/services/api", prefix: "services/api/", filterPath: "services/api/"},
+		{name: "other module path", dir: "tools", modulePath: "example.com/tooling", prefix: "tooling/", filterPath: "tools/"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gitRoot := t.TempDir()
+			dir := filepath.Join(gitRoot, test.dir)
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module "+test.modulePath+"\n\ngo 1.21\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			prefix, filterPath, err := prefixFromGoMod(gitRoot, dir)
+			if err != nil {
+				t.Fatalf("prefixFromGoMod() error: %v", err)
+			}
+			if prefix != test.prefix || filterPath != test.filterPath {
+				t.Errorf("prefixFromGoMod() = %q, %q, want %q, %q", prefix, filterPath, test.prefix, test.filterPath)
+			}
+		})
+	}
+}
+
+func TestPrefixFromGoModOutsideRepository(t *testing.T) {
+	gitRoot, dir := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/other\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := prefixFromGoMod(gitRoot, dir); err == nil {
+		t.Error("prefixFromGoMod() succeeded outside the repository")
+	}
+}
+
+func TestPrefixFromGoModFromSubdirectory(t *testing.T) {
+	gitRoot := t.TempDir()
+	for _, dir := range []string{"api", "web"} {
+		if err := os.MkdirAll(filepath.Join(gitRoot, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(gitRoot, "api", "go.mod"), []byte("module example.com/repo/api\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// the directory is found from the repository root, not from where it runs
+	chdir(t, filepath.Join(gitRoot, "web"))
+	prefix, filterPath, err := prefixFromGoMod(gitRoot, "api")
+	if err != nil {
+		t.Fatalf("prefixFromGoMod() error: %v", err)
+	}
+	if prefix != "api/" || filterPath != "api/" {
+		t.Errorf("prefixFromGoMod() = %q, %q, want %q, %q", prefix, filterPath, "api/", "api/")
+	}
+}
+
+func TestPrefixFromGoModWithoutWorktree(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/repo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, dir)
+	if _, _, err := prefixFromGoMod("", "."); err == nil {
+		t.Error("prefixFromGoMod() succeeded without worktree")
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package semver
+
+import "testing"
+
+func TestBaseTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseTag string
+		want    string
+		wantErr bool
+	}{
+		{name: "latest", want: "v1.2.1"},
+		{name: "older release", baseTag: "v1.1.3", want: "v1.2.0"},
+		{name: "annotated", baseTag: "v1.0.0", want: "v1.1.0"},
+		{name: "missing", baseTag: "v0.9.0", wantErr: true},
+		{name: "not a version", baseTag: "latest", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("feat: a")
+			r.annotate("v1.0.0")
+			r.commit("fix: b")
+			r.tag("v1.1.3")
+			r.tag("latest")
+			r.commit("feat: c")
+			r.tag("v1.2.0")
+			r.commit("fix: hotfix")
+
+			opts := options()
+			opts.BaseTag = test.baseTag
+			version, err := NewConventionalCommits(r.repo, opts).SemVer()
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("SemVer() = %s, want an error", version)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SemVer() error: %v", err)
+			}
+			if got := version.String(); got != test.want {
+				t.Errorf("SemVer() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
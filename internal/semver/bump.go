@@ -0,0 +1,211 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package semver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// BumpLevel is the increment a commit calls for
+type BumpLevel int
+
+const (
+	// BumpUndecided leaves the decision to the conventional commit rules
+	BumpUndecided BumpLevel = iota
+	BumpNone
+	BumpPatch
+	BumpMinor
+	BumpMajor
+)
+
+func (b BumpLevel) String() string {
+	switch b {
+	case BumpNone:
+		return "none"
+	case BumpPatch:
+		return "patch"
+	case BumpMinor:
+		return "minor"
+	case BumpMajor:
+		return "major"
+	default:
+		return "undecided"
+	}
+}
+
+// ParseBumpLevel returns the bump level of its name, like patch
+func ParseBumpLevel(name string) (BumpLevel, error) {
+	for _, level := range []BumpLevel{BumpNone, BumpPatch, BumpMinor, BumpMajor} {
+		if name == level.String() {
+			return level, nil
+		}
+	}
+	return BumpUndecided, fmt.Errorf("unknown bump level '%s'", name)
+}
+
+type VersionBump struct {
+	major   bool
+	minor   bool
+	patch   bool
+	reasons []BumpReason
+	// others are the hashes of the commits that don't bump
+	others []string
+}
+
+// Merge combines the bumps of two traversals, dropping commits seen twice
+func (vb VersionBump) Merge(other VersionBump) VersionBump {
+	merged := VersionBump{
+		major:   vb.major || other.major,
+		minor:   vb.minor || other.minor,
+		patch:   vb.patch || other.patch,
+		reasons: mergeReasons(vb.reasons, other.reasons),
+	}
+	seen := map[string]bool{}
+	for _, hash := range append(append([]string{}, vb.others...), other.others...) {
+		if !seen[hash] {
+			seen[hash] = true
+			merged.others = append(merged.others, hash)
+		}
+	}
+	return merged
+}
+
+// mergeReasons combines the reasons of the traversals, dropping commits seen twice
+func mergeReasons(traversals ...[]BumpReason) []BumpReason {
+	seen := map[string]bool{}
+	reasons := []BumpReason{}
+	for _, traversal := range traversals {
+		for _, reason := range traversal {
+			if !seen[reason.Hash] {
+				seen[reason.Hash] = true
+				reasons = append(reasons, reason)
+			}
+		}
+	}
+	return reasons
+}
+
+// Highest returns the name of the highest bump level of the commits, like
+// minor, none without any
+func (vb VersionBump) Highest() string {
+	return vb.level().String()
+}
+
+// level returns the highest bump level of the commits, BumpNone without any
+func (vb VersionBump) level() BumpLevel {
+	switch {
+	case vb.major:
+		return BumpMajor
+	case vb.minor:
+		return BumpMinor
+	case vb.patch:
+		return BumpPatch
+	default:
+		return BumpNone
+	}
+}
+
+// BumpReason is a commit that contributed to the version bump
+type BumpReason struct {
+	Hash    string `json:"hash"`
+	Subject string `json:"subject"`
+	Bump    string `json:"bump"`
+}
+
+// add registers the bump level of a commit
+func (vb *VersionBump) add(commit *object.Commit, level BumpLevel) {
+	switch level {
+	case BumpMajor:
+		vb.major = true
+	case BumpMinor:
+		vb.minor = true
+	case BumpPatch:
+		vb.patch = true
+	default:
+		vb.others = append(vb.others, commit.Hash.String())
+		return
+	}
+	vb.reasons = append(vb.reasons, BumpReason{
+		Hash:    commit.Hash.String(),
+		Subject: strings.SplitN(commit.Message, "\n", 2)[0],
+		Bump:    level.String(),
+	})
+}
+
+// Stats counts the relevant commits since the previous version by bump level
+type Stats struct {
+	Breaking int `json:"breaking"`
+	Features int `json:"features"`
+	Fixes    int `json:"fixes"`
+	Other    int `json:"other"`
+}
+
+// newStats counts the reasons and the other commits, dropping commits seen twice
+func newStats(reasons []BumpReason, others ...[]string) Stats {
+	var stats Stats
+	for _, reason := range reasons {
+		switch reason.Bump {
+		case BumpMajor.String():
+			stats.Breaking += 1
+		case BumpMinor.String():
+			stats.Features += 1
+		case BumpPatch.String():
+			stats.Fixes += 1
+		}
+	}
+	seen := map[string]bool{}
+	for _, traversal := range others {
+		for _, hash := range traversal {
+			if !seen[hash] {
+				seen[hash] = true
+				stats.Other += 1
+			}
+		}
+	}
+	return stats
+}
+
+// EscalationPolicy escalates a number of smaller bumps to a larger one, zero
+// thresholds disable the escalation
+type EscalationPolicy struct {
+	// Patches is the number of patches that escalate to a minor
+	Patches int
+	// Minors is the number of minors that escalate to a major
+	Minors int
+}
+
+// escalate returns the bump level the reasons escalate to, BumpNone if the
+// thresholds aren't reached
+func (ep EscalationPolicy) escalate(reasons []BumpReason) BumpLevel {
+	var patches, minors int
+	for _, reason := range reasons {
+		switch reason.Bump {
+		case BumpPatch.String():
+			patches += 1
+		case BumpMinor.String():
+			minors += 1
+		}
+	}
+	switch {
+	case ep.Minors > 0 && minors >= ep.Minors:
+		return BumpMajor
+	case ep.Patches > 0 && patches >= ep.Patches:
+		return BumpMinor
+	default:
+		return BumpNone
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package semver
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// bumpOf returns the version bump of the commits, each a hash and its level
+func bumpOf(commits ...interface{}) VersionBump {
+	var vb VersionBump
+	for i := 0; i < len(commits); i += 2 {
+		commit := &object.Commit{Hash: plumbing.NewHash(commits[i].(string)), Message: commits[i].(string)}
+		vb.add(commit, commits[i+1].(BumpLevel))
+	}
+	return vb
+}
+
+func TestVersionBumpHighest(t *testing.T) {
+	tests := []struct {
+		name string
+		bump VersionBump
+		want string
+	}{
+		{name: "empty", bump: VersionBump{}, want: "none"},
+		{name: "other", bump: bumpOf("aa", BumpNone), want: "none"},
+		{name: "patch", bump: bumpOf("aa", BumpPatch, "bb", BumpNone), want: "patch"},
+		{name: "minor", bump: bumpOf("aa", BumpPatch, "bb", BumpMinor), want: "minor"},
+		{name: "major", bump: bumpOf("aa", BumpMajor, "bb", BumpMinor, "cc", BumpPatch), want: "major"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.bump.Highest(); got != test.want {
+				t.Errorf("Highest() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestVersionBumpMerge(t *testing.T) {
+	tests := []struct {
+		name        string
+		a, b        VersionBump
+		wantHighest string
+		wantReasons []string
+		wantOthers  int
+	}{
+		{name: "empty", wantHighest: "none", wantReasons: []string{}},
+		{name: "empty with patch", b: bumpOf("aa", BumpPatch), wantHighest: "patch", wantReasons: []string{"aa"}},
+		{name: "patch with minor", a: bumpOf("aa", BumpPatch), b: bumpOf("bb", BumpMinor), wantHighest: "minor", wantReasons: []string{"aa", "bb"}},
+		{name: "major with minor", a: bumpOf("aa", BumpMajor), b: bumpOf("bb", BumpMinor), wantHighest: "major", wantReasons: []string{"aa", "bb"}},
+		{name: "minor with major", a: bumpOf("aa", BumpMinor), b: bumpOf("bb", BumpMajor), wantHighest: "major", wantReasons: []string{"aa", "bb"}},
+		{
+			name:        "commits seen twice",
+			a:           bumpOf("aa", BumpPatch, "cc", BumpNone),
+			b:           bumpOf("aa", BumpPatch, "bb", BumpMinor, "cc", BumpNone, "dd", BumpNone),
+			wantHighest: "minor",
+			wantReasons: []string{"aa", "bb"},
+			wantOthers:  2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			merged := test.a.Merge(test.b)
+			if got := merged.Highest(); got != test.wantHighest {
+				t.Errorf("Merge().Highest() = %s, want %s", got, test.wantHighest)
+			}
+			reasons := []string{}
+			for _, reason := range merged.reasons {
+				reasons = append(reasons, reason.Subject)
+			}
+			if !reflect.DeepEqual(reasons, test.wantReasons) {
+				t.Errorf("Merge() reasons = %v, want %v", reasons, test.wantReasons)
+			}
+			if got := len(merged.others); got != test.wantOthers {
+				t.Errorf("Merge() others = %d, want %d", got, test.wantOthers)
+			}
+			// merging is symmetric in the bump
+			if got := test.b.Merge(test.a).Highest(); got != test.wantHighest {
+				t.Errorf("reversed Merge().Highest() = %s, want %s", got, test.wantHighest)
+			}
+		})
+	}
+}
+
+func TestEscalation(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   EscalationPolicy
+		fixes    int
+		features int
+		want     string
+	}{
+		{name: "no policy", fixes: 5, want: "v1.0.1"},
+		{name: "below the threshold", policy: EscalationPolicy{Patches: 5}, fixes: 4, want: "v1.0.1"},
+		{name: "patches to minor", policy: EscalationPolicy{Patches: 5}, fixes: 5, want: "v1.1.0"},
+		{name: "minors to major", policy: EscalationPolicy{Minors: 3}, features: 3, want: "v2.0.0"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("chore: init")
+			r.tag("v1.0.0")
+			for i := 0; i < test.fixes; i++ {
+				r.commit("fix: repair")
+			}
+			for i := 0; i < test.features; i++ {
+				r.commit("feat: add")
+			}
+
+			opts := options()
+			opts.Escalation = test.policy
+			if got := r.version(opts); got != test.want {
+				t.Errorf("SemVer() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("chore: init")
+	r.tag("v1.0.0")
+	r.checkout("feature")
+	r.commit("feat: a")
+	r.commit("fix: b")
+	r.commit("docs: c")
+	r.checkout("main")
+	r.commit("fix: d")
+	r.commit("feat!: e")
+	r.commit("chore: f")
+	r.merge("feature", "Merge branch 'feature'")
+
+	cc := NewConventionalCommits(r.repo, options())
+	if _, err := cc.SemVer(); err != nil {
+		t.Fatalf("SemVer() error: %v", err)
+	}
+	// both traversals walk the commits, which count once
+	want := Stats{Breaking: 1, Features: 1, Fixes: 2, Other: 3}
+	if got := cc.Explain().Stats; got != want {
+		t.Errorf("Stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestPrereleaseCounter(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(r *testRepo)
+		want  string
+	}{
+		{
+			name: "first release candidate",
+			build: func(r *testRepo) {
+				r.commit("feat: a")
+				r.tag("v1.2.0")
+				r.commit("feat: b")
+			},
+			want: "v1.3.0-rc.1",
+		},
+		{
+			name: "continues from the highest candidate",
+			build: func(r *testRepo) {
+				r.commit("feat: a")
+				r.tag("v1.2.0")
+				r.commit("feat: b")
+				r.tag("v1.3.0-rc.1")
+				r.commit("fix: c")
+				r.tag("v1.3.0-rc.2")
+				r.commit("fix: d")
+			},
+			want: "v1.3.0-rc.3",
+		},
+		{
+			name: "other channels don't count",
+			build: func(r *testRepo) {
+				r.commit("feat: a")
+				r.tag("v1.2.0")
+				r.commit("feat: b")
+				r.tag("v1.3.0-rc.2")
+				r.commit("fix: c")
+				r.tag("v1.3.0-rc.10-hotfix")
+				r.tag("v1.3.0-beta.7")
+				r.commit("fix: d")
+			},
+			want: "v1.3.0-rc.3",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			test.build(r)
+			opts := options()
+			opts.Prerelease = "rc"
+			if got := r.version(opts); got != test.want {
+				t.Errorf("SemVer() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDefaultBump(t *testing.T) {
+	tests := []struct {
+		name        string
+		defaultBump BumpLevel
+		build       func(r *testRepo)
+		want        string
+	}{
+		{
+			name: "without default bump",
+			build: func(r *testRepo) {
+				r.commit("Update the readme")
+			},
+			want: "v1.0.0",
+		},
+		{
+			name:        "non-conventional commits",
+			defaultBump: BumpPatch,
+			build: func(r *testRepo) {
+				r.commit("Update the readme")
+				r.commit("WIP")
+			},
+			want: "v1.0.1",
+		},
+		{
+			name:        "non-conventional commits merged into main",
+			defaultBump: BumpPatch,
+			build: func(r *testRepo) {
+				r.checkout("feature")
+				r.commit("Update the readme")
+				r.checkout("main")
+				r.merge("feature", "Merge branch 'feature'")
+			},
+			want: "v1.0.1",
+		},
+		{
+			name:        "tagged HEAD",
+			defaultBump: BumpPatch,
+			want:        "v1.0.0",
+		},
+		{
+			name:        "conventional commits decide",
+			defaultBump: BumpMinor,
+			build: func(r *testRepo) {
+				r.commit("Update the readme")
+				r.commit("fix: a")
+			},
+			want: "v1.0.1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("feat: init")
+			r.tag("v1.0.0")
+			if test.build != nil {
+				test.build(r)
+			}
+			opts := options()
+			opts.DefaultBump = test.defaultBump
+			if got := r.version(opts); got != test.want {
+				t.Errorf("SemVer() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFirstRelease(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  string
+		tag     string
+		commit  string
+		want    string
+		wantErr bool
+	}{
+		{name: "without commits", tag: "v0.5.3", commit: "chore: a", want: "v1.0.0"},
+		{name: "regardless of a feature", tag: "v0.5.3", commit: "feat: a", want: "v1.0.0"},
+		{name: "with prefix", prefix: "api", tag: "api-v0.5.3", commit: "fix: a", want: "api-v1.0.0"},
+		{name: "without tags", commit: "fix: a", want: "1.0.0"},
+		{name: "already stable", tag: "v1.2.0", commit: "fix: a", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("feat: init")
+			if test.tag != "" {
+				r.tag(test.tag)
+			}
+			r.commit(test.commit)
+			opts := options()
+			opts.Prefix = test.prefix
+			opts.FirstRelease = true
+			version, err := NewConventionalCommits(r.repo, opts).SemVer()
+			if (err != nil) != test.wantErr {
+				t.Fatalf("SemVer() error = %v, want error %t", err, test.wantErr)
+			}
+			if err == nil && version.String() != test.want {
+				t.Errorf("SemVer() = %s, want %s", version, test.want)
+			}
+		})
+	}
+}
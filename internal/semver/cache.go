@@ -0,0 +1,89 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package semver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// cacheEntry is the version calculated for a HEAD, reused while the key holds
+type cacheEntry struct {
+	Key         string       `json:"key"`
+	Version     *SemVer      `json:"version"`
+	Explanation *Explanation `json:"explanation"`
+}
+
+// cacheKey fingerprints what the version depends on: HEAD, its branch, the
+// options and the tags themselves
+func (cc *ConventionalCommits) cacheKey(head plumbing.Hash, tagRefs map[string]string) (string, error) {
+	branch, err := cc.HeadBranch()
+	if err != nil {
+		return "", err
+	}
+	// all options count, except those excluded from JSON as they don't
+	// affect the version
+	options, err := json.Marshal(cc.options)
+	if err != nil {
+		return "", fmt.Errorf("couldn't fingerprint options: %w", err)
+	}
+	parts := []string{head.String(), branch, string(options)}
+	var tags []string
+	for sha, name := range tagRefs {
+		tags = append(tags, sha+"="+name)
+	}
+	sort.Strings(tags)
+	sum := sha256.Sum256([]byte(strings.Join(append(parts, tags...), "\n")))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// loadCache returns the cached version of the key, nil on a miss
+func (cc *ConventionalCommits) loadCache(key string) *SemVer {
+	data, err := os.ReadFile(cc.cacheFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			cc.warnf("couldn't read cache %s: %v", cc.cacheFile, err)
+		}
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		cc.warnf("couldn't parse cache %s: %v", cc.cacheFile, err)
+		return nil
+	}
+	if entry.Key != key || entry.Version == nil || entry.Explanation == nil {
+		return nil
+	}
+	cc.explain = entry.Explanation
+	return entry.Version
+}
+
+// storeCache replaces the cache by the version of the key
+func (cc *ConventionalCommits) storeCache(key string, version *SemVer) {
+	data, err := json.Marshal(cacheEntry{Key: key, Version: version, Explanation: cc.explain})
+	if err != nil {
+		cc.warnf("couldn't encode cache: %v", err)
+		return
+	}
+	if err := os.WriteFile(cc.cacheFile, data, 0o644); err != nil {
+		cc.warnf("couldn't write cache %s: %v", cc.cacheFile, err)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package semver
+
+import (
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestCacheKeyOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		commits []string
+		change  func(opts *Options)
+	}{
+		{"default bump", []string{"chore: c"}, func(opts *Options) { opts.DefaultBump = BumpPatch }},
+		{"force bump", []string{"chore: c"}, func(opts *Options) { opts.ForceBump = BumpMajor }},
+		{"patch types", []string{"chore: c"}, func(opts *Options) { opts.PatchTypes = []string{"chore"} }},
+		{"escalation", []string{"fix: c", "fix: d"}, func(opts *Options) { opts.Escalation = EscalationPolicy{Patches: 2} }},
+		{"channels", []string{"fix: c"}, func(opts *Options) { opts.Channels = []Channel{{"feature", "alpha"}} }},
+		{"separator", []string{"fix: c"}, func(opts *Options) { opts.Separator = "-" }},
+		{"scan subject", []string{"fix: c\n\nBREAKING CHANGE: gone"}, func(opts *Options) { opts.ScanSubject = true }},
+		{"tag filter", []string{"fix: c"}, func(opts *Options) { opts.TagFilter = regexp.MustCompile(`^v0\.`) }},
+		{"draft tags", []string{"fix: c"}, func(opts *Options) { opts.DraftTags = regexp.MustCompile(`^v1\.0\.0$`) }},
+		{"ignore tags", []string{"fix: c"}, func(opts *Options) { opts.IgnoreTags = []string{"v1.0.0"} }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.checkout("feature")
+			r.commit("fix: a")
+			r.tag("v0.9.0")
+			r.commit("feat: b")
+			r.tag("v1.0.0")
+			for _, message := range test.commits {
+				r.commit(message)
+			}
+
+			changed := options()
+			test.change(&changed)
+			want := r.version(changed)
+
+			cached := options()
+			cached.CacheFile = filepath.Join(t.TempDir(), "semver.json")
+			if got := r.version(cached); got == want {
+				t.Fatalf("SemVer() = %s without the option as well", got)
+			}
+			test.change(&cached)
+			if got := r.version(cached); got != want {
+				t.Errorf("SemVer() = %s from cache, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestCacheReuse(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("fix: a")
+	r.tag("v1.0.0")
+	r.commit("feat: b")
+
+	opts := options()
+	opts.CacheFile = filepath.Join(t.TempDir(), "semver.json")
+	cc := NewConventionalCommits(r.repo, opts)
+	head := r.head()
+	key, err := cc.cacheKey(head, r.tagRefs())
+	if err != nil {
+		t.Fatalf("couldn't fingerprint: %v", err)
+	}
+	if got := r.version(opts); got != "v1.1.0" {
+		t.Fatalf("SemVer() = %s, want v1.1.0", got)
+	}
+	if cached := cc.loadCache(key); cached == nil || cached.String() != "v1.1.0" {
+		t.Errorf("loadCache() = %v, want v1.1.0", cached)
+	}
+
+	r.commit("fix: c")
+	key, err = cc.cacheKey(r.head(), r.tagRefs())
+	if err != nil {
+		t.Fatalf("couldn't fingerprint: %v", err)
+	}
+	if cached := cc.loadCache(key); cached != nil {
+		t.Errorf("loadCache() = %v after a commit, want a miss", cached)
+	}
+}
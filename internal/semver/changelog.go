@@ -0,0 +1,81 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package semver
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Release is a group of changelog entries, an empty tag is the next version
+type Release struct {
+	Tag     string       `json:"tag,omitempty"`
+	Changes []BumpReason `json:"changes"`
+}
+
+// Changelog returns the conventional commits since the given tag, grouped by
+// the tags in between when grouped. It uses the tags of the last calculated
+// version, so SemVer must be called first.
+func (cc *ConventionalCommits) Changelog(from string, grouped bool) ([]Release, error) {
+	head, err := cc.revisionHash()
+	if err != nil {
+		return nil, err
+	}
+	var stop plumbing.Hash
+	if from != "" {
+		ref, err := cc.gitRepo.Tag(from)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't find changelog tag '%s': %w", from, err)
+		}
+		if stop, err = cc.tagTarget(ref); err != nil {
+			return nil, fmt.Errorf("couldn't resolve changelog tag '%s', %w", from, err)
+		}
+	}
+
+	commits, err := cc.gitRepo.Log(&git.LogOptions{From: head, Order: git.LogOrderDFS})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get commits: %w", err)
+	}
+
+	var reached bool
+	releases := []Release{{}}
+	versionBump := &VersionBump{}
+	err = commits.ForEach(func(commit *object.Commit) error {
+		if commit.Hash == stop {
+			reached = true
+			return errStopIter
+		}
+		if tag := cc.tagRefs[commit.Hash.String()]; grouped && tag != "" {
+			releases[len(releases)-1].Changes = versionBump.reasons
+			releases = append(releases, Release{Tag: tag})
+			versionBump = &VersionBump{}
+		}
+		if cc.isRelevantCommit(commit) {
+			versionBump.add(commit, cc.classify(commit))
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopIter) {
+		return nil, fmt.Errorf("couldn't walk commits for the changelog: %w", err)
+	}
+	if from != "" && !reached {
+		return nil, fmt.Errorf("changelog tag '%s' isn't an ancestor of HEAD", from)
+	}
+	releases[len(releases)-1].Changes = versionBump.reasons
+	return releases, nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package semver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChangelog(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		grouped bool
+		want    map[string][]string
+		wantErr bool
+	}{
+		{
+			name: "since the previous tag",
+			from: "v2.0.0",
+			want: map[string][]string{"": {"fix: d"}},
+		},
+		{
+			name: "three releases",
+			from: "v1.0.0",
+			want: map[string][]string{"": {"fix: d", "feat!: c", "fix: b", "feat: a"}},
+		},
+		{
+			name:    "three releases grouped",
+			from:    "v1.0.0",
+			grouped: true,
+			want: map[string][]string{
+				"":       {"fix: d"},
+				"v2.0.0": {"feat!: c"},
+				"v1.1.1": {"fix: b"},
+				"v1.1.0": {"feat: a"},
+			},
+		},
+		{name: "not an ancestor", from: "v0.1.0", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("chore: init")
+			r.tag("v1.0.0")
+			r.commit("feat: a")
+			r.tag("v1.1.0")
+			r.commit("fix: b")
+			r.tag("v1.1.1")
+			r.commit("feat!: c")
+			r.tag("v2.0.0")
+			r.checkout("other")
+			r.commit("feat: elsewhere")
+			r.tag("v0.1.0")
+			r.checkout("main")
+			r.commit("fix: d")
+
+			cc := NewConventionalCommits(r.repo, options())
+			if _, err := cc.SemVer(); err != nil {
+				t.Fatalf("SemVer() error: %v", err)
+			}
+			releases, err := cc.Changelog(test.from, test.grouped)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("Changelog() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Changelog() error: %v", err)
+			}
+			got := map[string][]string{}
+			for _, release := range releases {
+				for _, change := range release.Changes {
+					got[release.Tag] = append(got[release.Tag], change.Subject)
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Changelog() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
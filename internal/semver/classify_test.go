@@ -0,0 +1,241 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package semver
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		message string
+		opts    Options
+		want    BumpLevel
+	}{
+		{message: "feat: add", want: BumpMinor},
+		{message: "feat(api): add", want: BumpMinor},
+		{message: "fix: repair", want: BumpPatch},
+		{message: "feat!: drop", want: BumpMajor},
+		{message: "refactor(api)!: drop", want: BumpMajor},
+		{message: "chore!: drop", want: BumpMajor},
+		{message: "perf!: drop", want: BumpMajor},
+		{message: "refactor!: drop", want: BumpMajor},
+		{message: "chore!: drop", opts: Options{BreakingTypes: []string{"feat", "fix"}}, want: BumpNone},
+		{message: "fix!: drop", opts: Options{BreakingTypes: []string{"feat", "fix"}}, want: BumpMajor},
+		{message: "docs: explain", want: BumpNone},
+		{message: "fix: a\n\nBREAKING CHANGE: gone", want: BumpMajor},
+		{message: "fix: a\n\nbody\n\nReviewed-by: me\nBREAKING CHANGE: gone\n", want: BumpMajor},
+		{message: "docs: explain BREAKING CHANGE: foo", want: BumpNone},
+		{message: "docs: explain\n\nmention of BREAKING CHANGE: in the body", want: BumpNone},
+		{message: "BREAKING CHANGE: in the subject", want: BumpNone},
+		{message: "fix: a\n\nBREAKING CHANGE: gone", opts: Options{ScanSubject: true}, want: BumpPatch},
+		{message: "feat:x", want: BumpNone},
+		{message: "feat:x", opts: Options{Lenient: true}, want: BumpMinor},
+		{message: "feat:  x", opts: Options{Lenient: true}, want: BumpMinor},
+		{message: "feat: x", opts: Options{Lenient: true}, want: BumpMinor},
+		{message: "fix(API):x", opts: Options{Lenient: true}, want: BumpPatch},
+		{message: "feat!:x", opts: Options{Lenient: true}, want: BumpMajor},
+		{message: "perf: faster", want: BumpNone},
+		{message: "perf: faster", opts: Options{PatchTypes: []string{"perf", "refactor"}}, want: BumpPatch},
+		{message: "refactor(api): simpler", opts: Options{PatchTypes: []string{"perf", "refactor"}}, want: BumpPatch},
+		{message: "fix: repair", opts: Options{PatchTypes: []string{"perf"}}, want: BumpPatch},
+		{message: "perf!: drop", opts: Options{PatchTypes: []string{"perf"}}, want: BumpMajor},
+		{message: "fix: a\n\nDEPRECATED: gone", opts: Options{BreakingKeywords: []string{"DEPRECATED:"}}, want: BumpMajor},
+		{message: "fix: a\n\nBREAKING CHANGE: gone\n" + strings.Repeat("x", 100), opts: Options{ScanBytes: 50}, want: BumpPatch},
+		{message: "fix: a\n\n" + strings.Repeat("x", 100) + "\nBREAKING CHANGE: gone", opts: Options{ScanBytes: 50}, want: BumpMajor},
+		{message: "fix: a\n\n" + strings.Repeat("x", 100) + "BREAKING CHANGE: gone", opts: Options{ScanBytes: 30}, want: BumpPatch},
+	}
+	for _, test := range tests {
+		t.Run(test.message, func(t *testing.T) {
+			cc := NewConventionalCommits(nil, test.opts)
+			if got := cc.classify(&object.Commit{Message: test.message}); got != test.want {
+				t.Errorf("classify() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+// BenchmarkClassify compares messages without a footer keyword, which skip
+// the footer regular expression, to those with one
+func BenchmarkClassify(b *testing.B) {
+	body := strings.Repeat("A line of the body explaining the change.\n", 200)
+	messages := map[string]string{
+		"subject":          "fix: repair the thing",
+		"body":             "fix: repair the thing\n\n" + body,
+		"body with footer": "fix: repair the thing\n\n" + body + "\nBREAKING CHANGE: gone",
+	}
+	cc := NewConventionalCommits(nil, Options{})
+	for name, message := range messages {
+		commit := &object.Commit{Message: message}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				cc.classify(commit)
+			}
+		})
+	}
+}
+
+// BenchmarkSemVer calculates the version of a large history of commits with
+// long bodies
+func BenchmarkSemVer(b *testing.B) {
+	r := newTestRepo(b)
+	r.commit("chore: init")
+	r.tag("v1.0.0")
+	body := strings.Repeat("A line of the body explaining the change.\n", 50)
+	for i := 0; i < 2000; i++ {
+		r.commit(fmt.Sprintf("fix: change %d\n\n%s", i, body), "CHANGELOG.md")
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if got := r.version(options()); got != "v1.0.1" {
+			b.Fatalf("SemVer() = %s, want v1.0.1", got)
+		}
+	}
+}
+
+func TestClassifier(t *testing.T) {
+	// commits touching the API bump minor, others are left to the rules
+	touchesAPI := func(commit *object.Commit) BumpLevel {
+		stats, err := commit.Stats()
+		if err != nil {
+			return BumpUndecided
+		}
+		for _, stat := range stats {
+			if stat.Name == "api.proto" {
+				return BumpMinor
+			}
+		}
+		return BumpUndecided
+	}
+	tests := []struct {
+		name    string
+		message string
+		file    string
+		want    string
+	}{
+		{name: "touching the file", message: "chore: regenerate", file: "api.proto", want: "v1.1.0"},
+		{name: "other file", message: "chore: regenerate", file: "README.md", want: "v1.0.0"},
+		{name: "undecided", message: "fix: repair", file: "README.md", want: "v1.0.1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("chore: init")
+			r.tag("v1.0.0")
+			r.commit(test.message, test.file)
+
+			opts := options()
+			opts.Classifier = touchesAPI
+			if got := r.version(opts); got != test.want {
+				t.Errorf("SemVer() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestScanSubject(t *testing.T) {
+	// a revert quoting the reverted message, footer included
+	message := "fix: revert the removal\n\nThis reverts:\n\nfeat: remove the endpoint\n\nBREAKING CHANGE: the endpoint is gone\n"
+	tests := []struct {
+		scanSubject bool
+		want        string
+	}{
+		{scanSubject: false, want: "v2.0.0"},
+		{scanSubject: true, want: "v1.0.1"},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.scanSubject), func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("chore: init")
+			r.tag("v1.0.0")
+			r.commit(message)
+
+			opts := options()
+			opts.ScanSubject = test.scanSubject
+			if got := r.version(opts); got != test.want {
+				t.Errorf("SemVer() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestScanBytesHugeBody(t *testing.T) {
+	body := strings.Repeat("A generated line of the body.\n", 40000)
+	tests := []struct {
+		name      string
+		message   string
+		scanBytes int
+		want      string
+	}{
+		{name: "footer within the bound", message: "fix: a\n\n" + body + "\nBREAKING CHANGE: gone", scanBytes: 8192, want: "v2.0.0"},
+		{name: "footer before the bound", message: "fix: a\n\nBREAKING CHANGE: gone\n" + body, scanBytes: 8192, want: "v1.0.1"},
+		{name: "unbounded", message: "fix: a\n\nBREAKING CHANGE: gone\n" + body, want: "v2.0.0"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("chore: init")
+			r.tag("v1.0.0")
+			r.commit(test.message, "CHANGELOG.md")
+			opts := options()
+			opts.ScanBytes = test.scanBytes
+			if got := r.version(opts); got != test.want {
+				t.Errorf("SemVer() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBatchCommits(t *testing.T) {
+	bors := "Merge #12 #15\n\n" +
+		"12: feat: add the login r=alice a=bob\n\nThe login form of the site.\n\n" +
+		"15: fix: correct a typo r=alice a=carol\n\n" +
+		"Co-authored-by: Bob <bob@example.com>\n"
+	tests := []struct {
+		name     string
+		message  string
+		patterns []*regexp.Regexp
+		want     string
+	}{
+		{name: "bors", message: bors, patterns: DefaultBatchPatterns, want: "v1.1.0"},
+		{name: "bors without patterns", message: bors, want: "v1.0.0"},
+		{name: "mergify", message: "Merge of #42\n\n#42: fix!: drop the old API\n", patterns: DefaultBatchPatterns, want: "v2.0.0"},
+		{
+			name:     "custom pattern",
+			message:  "Merge queue batch 7\n\nfix: a\nfix: b\n",
+			patterns: []*regexp.Regexp{regexp.MustCompile(`^Merge queue batch \d+$`)},
+			want:     "v1.0.1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("chore: init")
+			r.tag("v1.0.0")
+			r.commit(test.message, "CHANGELOG.md")
+			opts := options()
+			opts.BatchPatterns = test.patterns
+			if got := r.version(opts); got != test.want {
+				t.Errorf("SemVer() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -20,42 +20,354 @@
 package semver
 
 import (
+	"container/heap"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/cli/go-gh"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 )
 
+// errStopIter stops a commit iteration without failing it
+var errStopIter = errors.New("stop commit iteration")
+
 type ConventionalCommits struct {
-	gitRepo    *git.Repository
-	majorRegex *regexp.Regexp
-	minorRegex *regexp.Regexp
-	patchRegex *regexp.Regexp
-	filterPath string
-	prefix     string
+	gitRepo     *git.Repository
+	majorRegex  *regexp.Regexp
+	footerRegex *regexp.Regexp
+	footerKeys  []string
+	minorRegex  *regexp.Regexp
+	patchRegex  *regexp.Regexp
+	typeRegex   *regexp.Regexp
+	filterPath  string
+	prefix      string
+	isolated    bool
+	strict      bool
+	prBase      string
+	shortTag    bool
+	leadingV    string
+	maxCommits  int
+	classifier  func(*object.Commit) BumpLevel
+	remoteTags  string
+	stableDist  bool
+	escalation  EscalationPolicy
+	revision    string
+	baseTag     string
+	tagRefs     map[string]string
+	ignoreTags  map[string]bool
+	channels    []Channel
+	prerelease  string
+	defaultBump BumpLevel
+	forceBump   BumpLevel
+	cacheFile   string
+	options     Options
+	graduate    bool
+	linear      bool
+	tagFilter   *regexp.Regexp
+	draftTags   *regexp.Regexp
+	scanSubject bool
+	scanBytes   int
+	batches     []*regexp.Regexp
+	remoteAuth  transport.AuthMethod
+	parseOpts   ParseOptions
+	mainBranch  string
+	mainSource  string
+	logger      *log.Logger
+	explain     *Explanation
+}
+
+// Options configures the conventional commits analysis
+type Options struct {
+	// FilterPath limits the relevant commits to those touching this path
+	FilterPath string
+	// Prefix limits the tags to those starting with this prefix
+	Prefix string
+	// IsolatePrefix limits the tags to those with exactly the prefix, so
+	// without a prefix the tags of modules like api-v1.2.3 are skipped
+	IsolatePrefix bool
+	// Strict fails on tags that aren't entirely a version instead of skipping them
+	Strict bool
+	// Separator between the extended branch, commit distance and commit hash
+	Separator string
+	// TagPattern replaces the regular expression to parse tags, see CompilePattern
+	TagPattern *regexp.Regexp
+	// PlusPrerelease takes the + segment of legacy tags like 1.2.3+rc1 as prerelease
+	PlusPrerelease bool
+	// ShortTag omits a zero patch (and minor) from release tags, like v1.2 or v1
+	ShortTag bool
+	// PRBase is the base branch a pull request would be merged into
+	PRBase string
+	// LeadingV is put in front of the initial version, like the v in v0.1.0.
+	// The zero value keeps the initial version bare, like 0.1.0, where the
+	// commandline defaults to v.
+	LeadingV string
+	// BreakingTypes are the commit types that are breaking with a !, like feat!:
+	// (default any type)
+	BreakingTypes []string
+	// BreakingKeywords are the footer keywords that are breaking, like
+	// BREAKING CHANGE: (default BREAKING CHANGE)
+	BreakingKeywords []string
+	// PatchTypes are additional commit types (besides fix) that bump the patch
+	PatchTypes []string
+	// Lenient tolerates a missing or multiple spaces after the colon of a commit type
+	Lenient bool
+	// MaxCommits limits the commits walked to find a tag, 0 is unlimited
+	MaxCommits int
+	// Classifier decides the bump level of a commit, unless it returns BumpUndecided
+	Classifier func(*object.Commit) BumpLevel `json:"-"`
+	// RemoteTags is the remote whose tags are considered as well
+	RemoteTags string
+	// RemoteAuth authenticates with the remote
+	RemoteAuth transport.AuthMethod `json:"-"`
+	// StableDistance measures the commit distance from the last stable tag,
+	// ignoring prerelease tags in between
+	StableDistance bool
+	// Escalation escalates a number of smaller bumps to a larger one
+	Escalation EscalationPolicy
+	// Revision is the commit to calculate the version for (default HEAD)
+	Revision string
+	// BaseTag is the tag to increment from instead of the latest tag
+	BaseTag string
+	// IgnoreTags are the tags to calculate the version as if they don't exist
+	IgnoreTags []string
+	// TagFilter limits the tags to those matching, besides the prefix
+	TagFilter *regexp.Regexp
+	// DraftTags match the placeholder tags that don't count as released, like
+	// v1.3.0-draft
+	DraftTags *regexp.Regexp
+	// ScanSubject only scans the subject of commit messages, ignoring breaking
+	// change footers in the body
+	ScanSubject bool
+	// BatchPatterns match the subjects of merge bot commits batching several
+	// pull requests, the subjects listed in their body decide the bump
+	BatchPatterns []*regexp.Regexp
+	// ScanBytes limits the breaking change footers to the last bytes of
+	// commit messages, bounding the work on huge generated messages; 0 is
+	// unlimited
+	ScanBytes int
+	// MainBranch is the default branch of the repository, instead of asking
+	// GitHub
+	MainBranch string
+	// Channels replace the branch in the extended information by a prerelease
+	// channel, the first matching pattern wins
+	Channels []Channel
+	// DefaultBump is the bump of relevant commits since the tag when none of
+	// them calls for one, like patch for non-conventional messages
+	DefaultBump BumpLevel
+	// ForceBump overrides the bump of the commits, like from pull request
+	// labels, BumpUndecided leaves it to the commits
+	ForceBump BumpLevel
+	// Prerelease outputs a numbered prerelease of this channel, like rc for
+	// v1.3.0-rc.1, continuing the counter of the existing tags
+	Prerelease string
+	// Linear assumes a history without merges, walking the commits once
+	// instead of in main and branch order
+	Linear bool
+	// FirstRelease graduates a 0.x version to 1.0.0, regardless of the commits
+	FirstRelease bool
+	// CacheFile stores the version calculated for HEAD, to reuse it while
+	// HEAD, the options and the tags are unchanged
+	CacheFile string `json:"-"`
+	// Logger receives informational messages, nil discards them
+	Logger *log.Logger `json:"-"`
+}
+
+// DefaultBatchPatterns match the batch commits of bors and mergify
+var DefaultBatchPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^Merge( #\d+)+$`),
+	regexp.MustCompile(`^Merge of #\d+`),
+}
+
+// Channel maps the branches matching the pattern to a prerelease channel
+type Channel struct {
+	Pattern string
+	Channel string
+}
+
+// DefaultChannels map the common branch types to prerelease channels
+var DefaultChannels = []Channel{
+	{"feature/*", "alpha"},
+	{"hotfix/*", "beta"},
+	{"release/*", "rc"},
+}
+
+// Explanation describes how the last calculated version was derived
+type Explanation struct {
+	Commit   string `json:"commit"`
+	Previous string `json:"previous,omitempty"`
+	// MainTag and BranchTag are the tags the main and branch traversal
+	// stopped at, Previous is the one of them the version builds upon
+	MainTag   string       `json:"mainTag,omitempty"`
+	BranchTag string       `json:"branchTag,omitempty"`
+	Bump      string       `json:"bump"`
+	Reasons   []BumpReason `json:"reasons"`
+	Stats     Stats        `json:"stats"`
 }
 
-type VersionBump struct {
-	major bool
-	minor bool
-	patch bool
+type traversal struct {
+	latest      *SemVer
+	versionBump *VersionBump
+	tag         string
+	tagged      plumbing.Hash
 }
 
-func NewConventionalCommits(repo *git.Repository, filterPath, prefix string) *ConventionalCommits {
+func NewConventionalCommits(repo *git.Repository, opts Options) *ConventionalCommits {
+	logger := opts.Logger
+	if logger == nil {
+		logger = log.New(io.Discard, "", 0)
+	}
+	// the spec requires a single space after the colon
+	colon := ": "
+	if opts.Lenient {
+		colon = ": *"
+	}
+	// per spec any type is breaking with a !
+	breakingTypes := `[a-z]+`
+	if len(opts.BreakingTypes) > 0 {
+		breakingTypes = typesPattern(opts.BreakingTypes)
+	}
+	var breakingKeywords []string
+	for _, keyword := range opts.BreakingKeywords {
+		// the colon is part of the footer token, not the keyword
+		breakingKeywords = append(breakingKeywords, strings.TrimSuffix(keyword, ":"))
+	}
+	if len(breakingKeywords) == 0 {
+		breakingKeywords = []string{"BREAKING CHANGE"}
+	}
+	ignoreTags := map[string]bool{}
+	for _, name := range opts.IgnoreTags {
+		ignoreTags[name] = true
+	}
 	return &ConventionalCommits{
-		gitRepo:    repo,
-		majorRegex: regexp.MustCompile(`^(fix|feat)(\(.+\))?!: |BREAKING CHANGE: `),
-		minorRegex: regexp.MustCompile(`^feat(\(.+\))?: `),
-		patchRegex: regexp.MustCompile(`^fix(\(.+\))?: `),
-		filterPath: filterPath,
-		prefix:     prefix,
+		gitRepo:     repo,
+		majorRegex:  regexp.MustCompile(`^` + breakingTypes + `(\(.+\))?!` + colon),
+		footerRegex: regexp.MustCompile(`(?m)^` + typesPattern(breakingKeywords) + colon),
+		footerKeys:  breakingKeywords,
+		minorRegex:  regexp.MustCompile(`^feat(\(.+\))?` + colon),
+		patchRegex:  regexp.MustCompile(`^` + typesPattern(append([]string{"fix"}, opts.PatchTypes...)) + `(\(.+\))?` + colon),
+		typeRegex:   regexp.MustCompile(`^[a-z]+(\(.+\))?!?` + colon),
+		filterPath:  opts.FilterPath,
+		prefix:      opts.Prefix,
+		isolated:    opts.IsolatePrefix,
+		strict:      opts.Strict,
+		prBase:      opts.PRBase,
+		shortTag:    opts.ShortTag,
+		leadingV:    opts.LeadingV,
+		maxCommits:  opts.MaxCommits,
+		classifier:  opts.Classifier,
+		remoteTags:  opts.RemoteTags,
+		remoteAuth:  opts.RemoteAuth,
+		stableDist:  opts.StableDistance,
+		escalation:  opts.Escalation,
+		revision:    opts.Revision,
+		baseTag:     opts.BaseTag,
+		ignoreTags:  ignoreTags,
+		channels:    opts.Channels,
+		prerelease:  opts.Prerelease,
+		defaultBump: opts.DefaultBump,
+		forceBump:   opts.ForceBump,
+		cacheFile:   opts.CacheFile,
+		options:     opts,
+		graduate:    opts.FirstRelease,
+		linear:      opts.Linear,
+		tagFilter:   opts.TagFilter,
+		draftTags:   opts.DraftTags,
+		scanSubject: opts.ScanSubject,
+		scanBytes:   opts.ScanBytes,
+		batches:     opts.BatchPatterns,
+		mainBranch:  opts.MainBranch,
+		mainSource:  "flag",
+		parseOpts:   ParseOptions{Separator: opts.Separator, Pattern: opts.TagPattern, Strict: opts.Strict, PlusPrerelease: opts.PlusPrerelease},
+		logger:      logger,
+	}
+}
+
+// hasTagPrefix tells whether the tag starts with the prefix up to its
+// separator, so prefix api matches api-v1.2.3 and api/v1.2.3, not apiserver-v1.2.3
+func hasTagPrefix(name, prefix string) bool {
+	if strings.HasSuffix(prefix, "-") || strings.HasSuffix(prefix, "/") {
+		return strings.HasPrefix(name, prefix)
+	}
+	return strings.HasPrefix(name, prefix+"-") || strings.HasPrefix(name, prefix+"/")
+}
+
+// tagTarget returns the commit a tag refers to
+func (cc *ConventionalCommits) tagTarget(ref *plumbing.Reference) (plumbing.Hash, error) {
+	annotatedTag, err := cc.gitRepo.TagObject(ref.Hash())
+	switch err {
+	case nil:
+		return annotatedTag.Target, nil
+	case plumbing.ErrObjectNotFound:
+		// a lightweight tag refers to the commit itself
+		return ref.Hash(), nil
+	default:
+		return plumbing.ZeroHash, fmt.Errorf("couldn't read its tag object: %w", err)
 	}
 }
 
+// baseTagRefs returns the base tag as the only tag to increment from
+func (cc *ConventionalCommits) baseTagRefs() (map[string]string, error) {
+	ref, err := cc.gitRepo.Tag(cc.baseTag)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't find base tag '%s': %w", cc.baseTag, err)
+	}
+	if _, err := ParseSemVerWithOptions(cc.baseTag, cc.parseOpts); err != nil {
+		return nil, fmt.Errorf("couldn't parse base tag: %w", err)
+	}
+	sha, err := cc.tagTarget(ref)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't resolve base tag '%s', %w", cc.baseTag, err)
+	}
+	return map[string]string{sha.String(): cc.baseTag}, nil
+}
+
+// addTag registers the tag of a commit, keeping the highest version when a
+// commit has several tags
+func (cc *ConventionalCommits) addTag(tagRefs map[string]string, hash, name string) {
+	existing, ok := tagRefs[hash]
+	if !ok || cc.tagBefore(existing, name) {
+		tagRefs[hash] = name
+	}
+}
+
+// tagBefore tells whether tag a has a lower precedence than tag b, a release
+// outranks its prereleases and the name breaks any remaining tie
+func (cc *ConventionalCommits) tagBefore(a, b string) bool {
+	versionA, errA := ParseSemVerWithOptions(a, cc.parseOpts)
+	versionB, errB := ParseSemVerWithOptions(b, cc.parseOpts)
+	switch {
+	case errA != nil || errB != nil:
+		return a < b
+	case versionA.GreaterThan(versionB):
+		return false
+	case versionB.GreaterThan(versionA):
+		return true
+	case versionA.IsPrerelease() != versionB.IsPrerelease():
+		return versionA.IsPrerelease()
+	default:
+		return a < b
+	}
+}
+
+// typesPattern matches any of the given commit types
+func typesPattern(types []string) string {
+	quoted := make([]string, len(types))
+	for i, commitType := range types {
+		quoted[i] = regexp.QuoteMeta(commitType)
+	}
+	return "(" + strings.Join(quoted, "|") + ")"
+}
+
 // SemVer returns the calculated next semantic version
 func (cc *ConventionalCommits) SemVer() (*SemVer, error) {
 	tags, err := cc.gitRepo.Tags()
@@ -65,16 +377,40 @@ func (cc *ConventionalCommits) SemVer() (*SemVer, error) {
 
 	// map relevant tags to commit hashes
 	tagRefs := map[string]string{}
+	invalidTags := []string{}
+	totalTags := 0
+	isRelevantTag := func(name string) bool {
+		totalTags += 1
+		if cc.ignoreTags[name] {
+			return false
+		}
+		if cc.tagFilter != nil && !cc.tagFilter.MatchString(name) {
+			return false
+		}
+		if cc.draftTags != nil && cc.draftTags.MatchString(name) {
+			return false
+		}
+		if cc.prefix != "" && !hasTagPrefix(name, cc.prefix) {
+			return false
+		}
+		version, err := ParseSemVerWithOptions(name, cc.parseOpts)
+		if err != nil {
+			invalidTags = append(invalidTags, name)
+			return false
+		}
+		// versions of other namespaces aren't comparable
+		return !cc.isolated || PrefixName(version.Prefix) == PrefixName(cc.prefix)
+	}
+	localTags := map[string]bool{}
 	err = tags.ForEach(func(ref *plumbing.Reference) error {
-		if cc.prefix == "" || strings.HasPrefix(ref.Name().Short(), cc.prefix) {
-			var sha plumbing.Hash
-			annotatedTag, _ := cc.gitRepo.TagObject(ref.Hash())
-			if annotatedTag != nil {
-				sha = annotatedTag.Target
-			} else {
-				sha = ref.Hash()
+		localTags[ref.Name().Short()] = true
+		if isRelevantTag(ref.Name().Short()) {
+			sha, err := cc.tagTarget(ref)
+			if err != nil {
+				cc.warnf("skipping tag %s, %v", ref.Name().Short(), err)
+				return nil
 			}
-			tagRefs[sha.String()] = ref.Name().Short()
+			cc.addTag(tagRefs, sha.String(), ref.Name().Short())
 		}
 		return nil
 	})
@@ -82,159 +418,729 @@ func (cc *ConventionalCommits) SemVer() (*SemVer, error) {
 		return nil, fmt.Errorf("couldn't iterate tags: %w", err)
 	}
 
+	// add the tags that only exist on the remote
+	if cc.remoteTags != "" {
+		remoteTags, err := cc.listRemoteTags()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range sortedNames(remoteTags) {
+			if localTags[name] || !isRelevantTag(name) {
+				continue
+			}
+			// commits that haven't been fetched can't be an ancestor of HEAD
+			if _, err := cc.gitRepo.CommitObject(remoteTags[name]); err == nil {
+				cc.addTag(tagRefs, remoteTags[name].String(), name)
+			}
+		}
+	}
+	if cc.strict && len(invalidTags) > 0 {
+		return nil, fmt.Errorf("couldn't parse tags: %s", strings.Join(invalidTags, ", "))
+	}
+
+	cc.tagRefs = tagRefs
+
+	// the base tag replaces the discovered tags
+	if cc.baseTag != "" {
+		if tagRefs, err = cc.baseTagRefs(); err != nil {
+			return nil, err
+		}
+	}
+
+	head, err := cc.revisionHash()
+	if err != nil {
+		return nil, err
+	}
+	if shallow, err := cc.gitRepo.Storer.Shallow(); err == nil && len(shallow) > 0 {
+		cc.warnf("the repository is a shallow clone, tags beyond its depth are missed")
+	}
+
 	// no existing tags
 	if len(tagRefs) == 0 {
-		return NewSemVer(0, 1, 0), nil
+		switch {
+		case totalTags == 0:
+			cc.infof("no tags found in the repository, starting at the initial version")
+		case len(invalidTags) > 0:
+			cc.infof("none of the %d tags could be parsed as a version, starting at the initial version", totalTags)
+		default:
+			cc.infof("none of the %d tags match prefix '%s', starting at the initial version", totalTags, cc.prefix)
+		}
+		cc.explain = &Explanation{Commit: head.String(), Bump: "initial"}
+		initialVersion := NewSemVer(0, 1, 0)
+		if cc.graduate {
+			initialVersion = NewSemVer(1, 0, 0)
+		}
+		initialVersion.Prefix = cc.prefix
+		initialVersion.LeadingV = cc.leadingV
+		initialVersion.Separator = cc.parseOpts.Separator
+		initialVersion.ShortTag = cc.shortTag
+		return initialVersion, nil
+	}
+
+	// a classifier is code, which the cache key can't fingerprint
+	var cacheKey string
+	if cc.cacheFile != "" && cc.classifier == nil {
+		if cacheKey, err = cc.cacheKey(head, tagRefs); err != nil {
+			return nil, err
+		}
+		if cached := cc.loadCache(cacheKey); cached != nil {
+			cc.infof("reusing the version of %.7s from cache %s", head.String(), cc.cacheFile)
+			return cached, nil
+		}
 	}
 
-	// traverse main branch to find latest version
-	latestMain, mainVersionBump, err := cc.traverse(tagRefs, git.LogOrderDFS)
+	// Both traversals walk the ancestors of HEAD, so the version is always
+	// relative to what is being built. The main traversal follows the first
+	// parents first, the branch traversal the merged parents first.
+	mainTraversal, err := cc.traverse(tagRefs, head, git.LogOrderDFS)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't walk commits on main: %w", err)
 	}
-	mainBranch, err := cc.getMainBranch()
-	if err != nil {
-		return nil, fmt.Errorf("couldn't figure out main branch: %w", err)
+	latestMain, mainVersionBump := mainTraversal.latest, mainTraversal.versionBump
+
+	// on a linear history both orders walk the same chain
+	branchTraversal := mainTraversal
+	if !cc.linear {
+		if branchTraversal, err = cc.traverse(tagRefs, head, git.LogOrderDFSPost); err != nil {
+			return nil, fmt.Errorf("couldn't walk commits on branch: %w", err)
+		}
 	}
-	latestMain.SetBranch(mainBranch)
+	latestBranch, branchVersionBump := branchTraversal.latest, branchTraversal.versionBump
 
-	// traverse current branch to find latest version
-	latestBranch, branchVersionBump, err := cc.traverse(tagRefs, git.LogOrderDFSPost)
-	if err != nil {
-		return nil, fmt.Errorf("couldn't walk commits on branch: %w", err)
+	// a pull request would also release the commits on its base branch
+	baseTraversal := &traversal{versionBump: &VersionBump{}}
+	if cc.prBase != "" {
+		base, err := cc.gitRepo.ResolveRevision(plumbing.Revision(cc.prBase))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't resolve pull request base '%s': %w", cc.prBase, err)
+		}
+		baseTraversal, err = cc.traverse(tagRefs, *base, git.LogOrderDFS)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't walk commits on pull request base: %w", err)
+		}
 	}
-	head, err := cc.gitRepo.Head()
-	if err != nil {
-		return nil, fmt.Errorf("couldn't get head: %w", err)
+	latestBase, baseVersionBump := baseTraversal.latest, baseTraversal.versionBump
+
+	// might be in detached head state, a shallow clone or an unrelated history
+	if latestMain == nil && latestBranch == nil && latestBase == nil {
+		if cc.prefix != "" {
+			return nil, fmt.Errorf("tags with prefix '%s' exist in the repository, but not in ancestors of HEAD (is the clone shallow, or is the prefix tagged on another history?)", cc.prefix)
+		}
+		return nil, fmt.Errorf("tags exist in the repository, but not in ancestors of HEAD (is the clone shallow?)")
 	}
-	latestBranch.SetBranch(head.Name().Short())
 
-	// might be in detached head state
-	if latestMain == nil && latestBranch == nil {
-		return nil, fmt.Errorf("tags exist in the repository, but not in ancestors of HEAD")
+	// a traversal without tag walked the whole history, like when the tags are
+	// only reachable through a merged branch, so its commits don't count
+	if latestMain == nil {
+		mainVersionBump = &VersionBump{}
+	}
+	if latestBranch == nil {
+		branchVersionBump = &VersionBump{}
+	}
+	if latestBase == nil {
+		baseVersionBump = &VersionBump{}
 	}
 
-	// figure out the latest version in either parent
+	// figure out the latest version in either parent. On a tie only the tag
+	// differs, the extended information is derived from HEAD further down.
 	var latestVersion *SemVer
+	var latestTag string
 	if latestMain == nil {
-		latestVersion = latestBranch
+		latestVersion, latestTag = latestBranch, branchTraversal.tag
 	} else if latestBranch == nil {
-		latestVersion = latestMain
+		latestVersion, latestTag = latestMain, mainTraversal.tag
 	} else if latestMain.GreaterThan(latestBranch) {
-		latestVersion = latestMain
+		latestVersion, latestTag = latestMain, mainTraversal.tag
+	} else if !latestBranch.GreaterThan(latestMain) && cc.taggedLater(mainTraversal, branchTraversal) {
+		// equal versions, the more recently tagged commit wins
+		latestVersion, latestTag = latestMain, mainTraversal.tag
 	} else {
-		latestVersion = latestBranch
+		latestVersion, latestTag = latestBranch, branchTraversal.tag
+	}
+	if latestBase != nil && (latestVersion == nil || latestBase.GreaterThan(latestVersion)) {
+		latestVersion, latestTag = latestBase, baseTraversal.tag
 	}
 
 	// figure out the highest increment in either parent
+	merged := mainVersionBump.Merge(*branchVersionBump).Merge(*baseVersionBump)
+	reasons := merged.reasons
+	stats := newStats(reasons, merged.others)
+	level := merged.level()
+	if escalated := cc.escalation.escalate(reasons); escalated > level {
+		level = escalated
+	}
 	var newVersion SemVer
+	var bump string
 	switch {
-	case mainVersionBump.major || branchVersionBump.major:
-		newVersion = latestVersion.IncMajor()
-	case mainVersionBump.minor || branchVersionBump.minor:
-		newVersion = latestVersion.IncMinor()
-	case mainVersionBump.patch || branchVersionBump.patch:
-		newVersion = latestVersion.IncPatch()
+	case cc.forceBump == BumpNone:
+		newVersion, bump = *latestVersion, "none"
+	case cc.forceBump > BumpNone:
+		newVersion, bump = increment(latestVersion, cc.forceBump), cc.forceBump.String()
+	case level > BumpNone:
+		newVersion, bump = increment(latestVersion, level), level.String()
+	case cc.defaultBump > BumpNone && stats.Other > 0:
+		newVersion, bump = increment(latestVersion, cc.defaultBump), cc.defaultBump.String()
 	default:
-		newVersion = *latestVersion
+		newVersion, bump = *latestVersion, "none"
+	}
+	if cc.graduate {
+		if latestVersion.Major >= 1 {
+			return nil, fmt.Errorf("couldn't graduate to 1.0.0, tag '%s' is already stable", latestTag)
+		}
+		newVersion, bump = latestVersion.IncMajor(), "major"
+	}
+	if cc.prerelease != "" && latestVersion.Prerelease != "" && bump != "none" {
+		newVersion = prereleaseIncrement(latestVersion, newVersion, bump)
+	}
+
+	cc.explain = &Explanation{
+		Commit:    head.String(),
+		Previous:  latestTag,
+		MainTag:   mainTraversal.tag,
+		BranchTag: branchTraversal.tag,
+		Bump:      bump,
+		Reasons:   reasons,
+		Stats:     stats,
 	}
 
-	// drop extended information for main branch
-	if latestBranch.SameBranch(latestMain) {
+	// the main branch only decides whether to keep extended information
+	mainBranch, err := cc.MainBranch()
+	if err != nil {
+		return nil, err
+	}
+	headBranch, err := cc.HeadBranch()
+	if err != nil {
+		return nil, err
+	}
+	// derive the extended information from HEAD, the copy of the latest tag
+	// still shares what was parsed from it
+	newVersion.Ext = nil
+	newVersion.SetBranch(cc.channel(headBranch))
+	newVersion.SetCommitHash(head.String())
+	distanceRefs := tagRefs
+	if cc.stableDist {
+		distanceRefs = cc.stableTags(tagRefs)
+	}
+	commitDistance, err := cc.commitDistance(head, distanceRefs)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't determine commit distance: %w", err)
+	}
+	newVersion.SetCommitDistance(commitDistance)
+	// a tagged HEAD is the tag itself, on any branch
+	if headBranch == mainBranch && cc.prBase == "" || commitDistance == 0 {
 		newVersion.Ext = nil
 	}
+	// without a bump a stable release has nothing to prerelease
+	if cc.prerelease != "" && commitDistance > 0 && (bump != "none" || latestVersion.Prerelease != "") {
+		newVersion.Ext = nil
+		newVersion.Prerelease = fmt.Sprintf("%s.%d", cc.prerelease, cc.prereleaseCounter(&newVersion))
+	}
+	if cacheKey != "" {
+		cc.storeCache(cacheKey, &newVersion)
+	}
 	return &newVersion, nil
 }
 
-func (cc *ConventionalCommits) traverse(tagRefs map[string]string, order git.LogOrder) (*SemVer, *VersionBump, error) {
+func (cc *ConventionalCommits) infof(format string, args ...interface{}) {
+	cc.logger.Printf("info: "+format, args...)
+}
+
+func (cc *ConventionalCommits) warnf(format string, args ...interface{}) {
+	cc.logger.Printf("warning: "+format, args...)
+}
+
+// Explain returns how the version of the last SemVer call was derived
+func (cc *ConventionalCommits) Explain() *Explanation {
+	return cc.explain
+}
+
+func (cc *ConventionalCommits) traverse(tagRefs map[string]string, from plumbing.Hash, order git.LogOrder) (*traversal, error) {
 	versionBump := &VersionBump{}
 
-	var stopIter error = fmt.Errorf("stop commit iteration")
 	var latestTag string
+	var tagged plumbing.Hash
 
-	var commitDistance uint64 = 0
-	var commitHash string = ""
+	var walked int = 0
 
-	// walk commit hashes back from HEAD via main
-	commits, err := cc.gitRepo.Log(&git.LogOptions{Order: order})
+	// walk commit hashes back from the given commit
+	commits, err := cc.gitRepo.Log(&git.LogOptions{From: from, Order: order})
 	if err != nil {
-		return nil, versionBump, fmt.Errorf("couldn't get commits: %w", err)
+		return nil, fmt.Errorf("couldn't get commits: %w", err)
 	}
 
+	var relevant []*object.Commit
+	var merged bool
 	err = commits.ForEach(func(commit *object.Commit) error {
-		if commitHash == "" {
-			commitHash = commit.Hash.String()
+		if latestTag = tagRefs[commit.Hash.String()]; latestTag != "" {
+			tagged = commit.Hash
+			return errStopIter
+		}
+		walked += 1
+		if cc.maxCommits > 0 && walked > cc.maxCommits {
+			return fmt.Errorf("no tag found within %d commits", cc.maxCommits)
 		}
 
-		if latestTag = tagRefs[commit.Hash.String()]; latestTag != "" {
-			return stopIter
+		merged = merged || commit.NumParents() > 1
+		if cc.isRelevantCommit(commit) {
+			relevant = append(relevant, commit)
 		}
-		commitDistance += 1
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopIter) {
+		return nil, fmt.Errorf("couldn't determine latest tag: %w", err)
+	}
 
-		if relevant := cc.isRelevantCommit(commit); relevant {
-			// analyze commit message
-			if cc.patchRegex.MatchString(commit.Message) {
-				versionBump.patch = true
-			}
-			if cc.minorRegex.MatchString(commit.Message) {
-				versionBump.minor = true
-			}
-			if cc.majorRegex.MatchString(commit.Message) {
-				versionBump.major = true
+	// past a merge the walk may reach commits below the tag of the other
+	// parent before the tag itself, which were released already
+	if merged && latestTag != "" {
+		start, err := cc.gitRepo.CommitObject(from)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't get commit %s: %w", from, err)
+		}
+		untagged, err := untaggedCommits(start, tagRefs)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't determine released commits: %w", err)
+		}
+		var unreleased []*object.Commit
+		for _, commit := range relevant {
+			if untagged[commit.Hash] {
+				unreleased = append(unreleased, commit)
 			}
 		}
-		return err
-	})
-	if err != nil && err != stopIter {
-		return nil, versionBump, fmt.Errorf("couldn't determine latest tag: %w", err)
+		relevant = unreleased
+	}
+	for _, commit := range relevant {
+		versionBump.add(commit, cc.classify(commit))
 	}
 
 	// not tagged yet. this can happen if we are on a branch with no tags.
 	if latestTag == "" {
-		return nil, versionBump, nil
+		return &traversal{versionBump: versionBump}, nil
 	}
 
 	// parse
-	latestVersion, err := ParseSemVer(latestTag)
+	latestVersion, err := ParseSemVerWithOptions(latestTag, cc.parseOpts)
 	if err != nil {
-		return nil, versionBump, fmt.Errorf("couldn't parse tag '%v': %w", latestTag, err)
+		return nil, fmt.Errorf("couldn't parse tag '%v': %w", latestTag, err)
 	}
 
 	// set extended information
+	latestVersion.Separator = cc.parseOpts.Separator
+	latestVersion.ShortTag = cc.shortTag
 	latestVersion.SetBranch("")
-	latestVersion.SetCommitDistance(commitDistance)
-	latestVersion.SetCommitHash(commitHash)
-	return latestVersion, versionBump, nil
+	// the commit walked from, not the tagged one, so an amended or rebased
+	// HEAD shows its own hash
+	latestVersion.SetCommitHash(from.String())
+	return &traversal{latest: latestVersion, versionBump: versionBump, tag: latestTag, tagged: tagged}, nil
+}
+
+// taggedLater tells whether the tagged commit of traversal a was committed
+// after the one of traversal b, false when either time is unknown
+func (cc *ConventionalCommits) taggedLater(a, b *traversal) bool {
+	commitA, errA := cc.gitRepo.CommitObject(a.tagged)
+	commitB, errB := cc.gitRepo.CommitObject(b.tagged)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return commitA.Committer.When.After(commitB.Committer.When)
+}
+
+// commitDistance counts the commits reachable from the given commit but not
+// from a tagged ancestor, like git rev-list --count HEAD ^tag... does.
+func (cc *ConventionalCommits) commitDistance(from plumbing.Hash, tagRefs map[string]string) (uint64, error) {
+	start, err := cc.gitRepo.CommitObject(from)
+	if err != nil {
+		return 0, err
+	}
+	untagged, err := untaggedCommits(start, tagRefs)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(len(untagged)), nil
+}
+
+// untaggedCommits returns the commits reachable from the commit but not from a
+// tagged ancestor
+func untaggedCommits(start *object.Commit, tagRefs map[string]string) (map[plumbing.Hash]bool, error) {
+	// tags on descendants, like a newer release of main when HEAD is behind
+	// it, don't exclude anything
+	tagged, err := taggedAncestors(start, tagRefs)
+	if err != nil {
+		return nil, err
+	}
+
+	// walk the newest commits first, marking the ancestors of the tagged
+	// commits as excluded, until only excluded commits are left to walk
+	excluded := map[plumbing.Hash]bool{}
+	queue := &commitQueue{}
+	push := func(commit *object.Commit, exclude bool) {
+		if wasExcluded, seen := excluded[commit.Hash]; seen && (wasExcluded || !exclude) {
+			return
+		}
+		excluded[commit.Hash] = exclude
+		heap.Push(queue, queuedCommit{commit, exclude})
+	}
+	push(start, false)
+	for _, commit := range tagged {
+		push(commit, true)
+	}
+	for queue.included > 0 {
+		item := heap.Pop(queue).(queuedCommit)
+		// a later path may have excluded the commit since it was queued
+		exclude := excluded[item.commit.Hash]
+		err := item.commit.Parents().ForEach(func(parent *object.Commit) error {
+			push(parent, exclude)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	untagged := map[plumbing.Hash]bool{}
+	for hash, exclude := range excluded {
+		if !exclude {
+			untagged[hash] = true
+		}
+	}
+	return untagged, nil
+}
+
+// taggedAncestors returns the nearest tagged commits the commit descends
+// from, the commit itself when tagged. The tags behind them are their
+// ancestors too, which the walk of commitDistance excludes anyway.
+func taggedAncestors(start *object.Commit, tagRefs map[string]string) ([]*object.Commit, error) {
+	var tagged []*object.Commit
+	if len(tagRefs) == 0 {
+		return tagged, nil
+	}
+	seen := map[plumbing.Hash]bool{start.Hash: true}
+	queue := []*object.Commit{start}
+	for len(queue) > 0 {
+		commit := queue[0]
+		queue = queue[1:]
+		if _, ok := tagRefs[commit.Hash.String()]; ok {
+			tagged = append(tagged, commit)
+			continue
+		}
+		err := commit.Parents().ForEach(func(parent *object.Commit) error {
+			if !seen[parent.Hash] {
+				seen[parent.Hash] = true
+				queue = append(queue, parent)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tagged, nil
+}
+
+// queuedCommit is a commit to walk by commitDistance
+type queuedCommit struct {
+	commit  *object.Commit
+	exclude bool
+}
+
+// commitQueue is a heap of commits, the most recently committed first, that
+// counts the commits queued as included
+type commitQueue struct {
+	items    []queuedCommit
+	included int
+}
+
+func (q *commitQueue) Len() int { return len(q.items) }
+
+func (q *commitQueue) Less(i, j int) bool {
+	return q.items[i].commit.Committer.When.After(q.items[j].commit.Committer.When)
+}
+
+func (q *commitQueue) Swap(i, j int) { q.items[i], q.items[j] = q.items[j], q.items[i] }
+
+func (q *commitQueue) Push(x interface{}) {
+	item := x.(queuedCommit)
+	if !item.exclude {
+		q.included += 1
+	}
+	q.items = append(q.items, item)
+}
+
+func (q *commitQueue) Pop() interface{} {
+	item := q.items[len(q.items)-1]
+	q.items = q.items[:len(q.items)-1]
+	if !item.exclude {
+		q.included -= 1
+	}
+	return item
+}
+
+// BuildNumber returns the number of commits reachable from HEAD (or the
+// revision), which increases with every commit on a branch
+func (cc *ConventionalCommits) BuildNumber() (uint64, error) {
+	head, err := cc.revisionHash()
+	if err != nil {
+		return 0, err
+	}
+	buildNumber, err := cc.commitDistance(head, nil)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't count commits: %w", err)
+	}
+	return buildNumber, nil
+}
+
+// increment returns the version incremented by the bump level
+func increment(version *SemVer, level BumpLevel) SemVer {
+	switch level {
+	case BumpMajor:
+		return version.IncMajor()
+	case BumpMinor:
+		return version.IncMinor()
+	default:
+		return version.IncPatch()
+	}
+}
+
+// prereleaseIncrement keeps the core of a prerelease that already holds the
+// bump, so further commits after v1.3.0-rc.2 stay on v1.3.0
+func prereleaseIncrement(latest *SemVer, next SemVer, bump string) SemVer {
+	switch {
+	case bump == "major" && (latest.Minor != 0 || latest.Patch != 0):
+		return next
+	case bump == "minor" && latest.Patch != 0:
+		return next
+	}
+	core := *latest
+	core.Prerelease, core.Metadata = "", ""
+	return core
+}
+
+// prereleaseCounter returns the number following the highest prerelease of
+// the channel tagged for the same core, starting at 1
+func (cc *ConventionalCommits) prereleaseCounter(version *SemVer) uint64 {
+	var highest uint64
+	for _, name := range cc.tagRefs {
+		tagged, err := ParseSemVerWithOptions(name, cc.parseOpts)
+		if err != nil || tagged.Core() != version.Core() {
+			continue
+		}
+		counter, found := strings.CutPrefix(tagged.Prerelease, cc.prerelease+".")
+		if !found {
+			continue
+		}
+		if n, err := strconv.ParseUint(counter, 10, 64); err == nil && n > highest {
+			highest = n
+		}
+	}
+	return highest + 1
+}
+
+// stableTags filters the prerelease tags out of the tag references
+func (cc *ConventionalCommits) stableTags(tagRefs map[string]string) map[string]string {
+	stableRefs := map[string]string{}
+	for sha, name := range tagRefs {
+		if version, err := ParseSemVerWithOptions(name, cc.parseOpts); err == nil && !version.IsPrerelease() {
+			stableRefs[sha] = name
+		}
+	}
+	return stableRefs
+}
+
+// classify determines the bump level of a commit, consulting the custom
+// classifier before the conventional commit rules
+func (cc *ConventionalCommits) classify(commit *object.Commit) BumpLevel {
+	if cc.classifier != nil {
+		if level := cc.classifier(commit); level != BumpUndecided {
+			return level
+		}
+	}
+
+	// analyze the subject, the body only matters for a breaking change footer
+	// unless only the subject is scanned
+	subject := commit.Message
+	if i := strings.IndexByte(subject, '\n'); i >= 0 {
+		subject = subject[:i]
+	}
+	// footers are at the end of the body, which follows the first blank line
+	_, footers, _ := strings.Cut(commit.Message, "\n\n")
+	if cc.scanBytes > 0 && len(footers) > cc.scanBytes {
+		// skip the line the limit cuts through
+		footers = footers[len(footers)-cc.scanBytes:]
+		if i := strings.IndexByte(footers, '\n'); i >= 0 {
+			footers = footers[i+1:]
+		}
+	}
+	if !cc.scanSubject && cc.hasBreakingFooter(footers) {
+		return BumpMajor
+	}
+	for _, pattern := range cc.batches {
+		if pattern.MatchString(subject) {
+			return cc.classifyBatch(commit.Message)
+		}
+	}
+	return cc.classifySubject(subject)
+}
+
+// classifySubject returns the bump level of a commit subject
+func (cc *ConventionalCommits) classifySubject(subject string) BumpLevel {
+	switch {
+	case cc.majorRegex.MatchString(subject):
+		return BumpMajor
+	case cc.minorRegex.MatchString(subject):
+		return BumpMinor
+	case cc.patchRegex.MatchString(subject):
+		return BumpPatch
+	default:
+		return BumpNone
+	}
+}
+
+// batchLinePrefix is the pull request number merge bots put before the
+// batched subjects, like 123: feat: add a thing
+var batchLinePrefix = regexp.MustCompile(`^#?\d+:\s*`)
+
+// classifyBatch returns the highest bump level of the subjects a merge bot
+// listed in the body of a batch commit
+func (cc *ConventionalCommits) classifyBatch(message string) BumpLevel {
+	level := BumpNone
+	lines := strings.Split(message, "\n")
+	for _, line := range lines[1:] {
+		subject := batchLinePrefix.ReplaceAllString(strings.TrimSpace(line), "")
+		if lineLevel := cc.classifySubject(subject); lineLevel > level {
+			level = lineLevel
+		}
+	}
+	return level
+}
+
+// Lint returns the bump level of a commit message and whether it is a
+// conventional commit at all
+func (cc *ConventionalCommits) Lint(message string) (BumpLevel, bool) {
+	level := cc.classify(&object.Commit{Message: message})
+	return level, level != BumpNone || cc.typeRegex.MatchString(message)
+}
+
+// hasBreakingFooter tells whether the body has a breaking change footer line,
+// only running the regular expression when a keyword is present
+func (cc *ConventionalCommits) hasBreakingFooter(body string) bool {
+	for _, keyword := range cc.footerKeys {
+		if strings.Contains(body, keyword) {
+			return cc.footerRegex.MatchString(body)
+		}
+	}
+	return false
 }
 
 func (cc *ConventionalCommits) isRelevantCommit(commit *object.Commit) bool {
 	// With no filtering, each commit is relevant
-	if cc.prefix == "" {
+	if cc.filterPath == "" {
 		return true
 	}
 
-	// Filter on path
-	fileIter, err := commit.Files()
+	// Filter on the paths changed compared to the first parent
+	tree, err := commit.Tree()
+	if err != nil {
+		return true
+	}
+	var parentTree *object.Tree
+	if commit.NumParents() > 0 {
+		parent, err := commit.Parent(0)
+		if err != nil {
+			return true
+		}
+		if parentTree, err = parent.Tree(); err != nil {
+			return true
+		}
+	}
+	changes, err := object.DiffTree(parentTree, tree)
 	if err != nil {
 		return true
 	}
+	for _, change := range changes {
+		if strings.HasPrefix(change.From.Name, cc.filterPath) || strings.HasPrefix(change.To.Name, cc.filterPath) {
+			return true
+		}
+	}
+	return false
+}
 
-	var relevant = false
-	fileIter.ForEach(func(file *object.File) error {
-		if !relevant && strings.HasPrefix(file.Name, cc.filterPath) {
-			relevant = true
+// channel returns the prerelease channel of the branch, or the branch itself
+func (cc *ConventionalCommits) channel(branch string) string {
+	for _, channel := range cc.channels {
+		if matched, _ := path.Match(channel.Pattern, branch); matched {
+			return channel.Channel
 		}
-		return nil
-	})
-	return relevant
+	}
+	return branch
 }
 
-func (cc *ConventionalCommits) getMainBranch() (string, error) {
+// revisionHash resolves the revision to calculate the version for, HEAD by default
+func (cc *ConventionalCommits) revisionHash() (plumbing.Hash, error) {
+	if cc.revision == "" {
+		head, err := cc.gitRepo.Head()
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("couldn't get head: %w", err)
+		}
+		return head.Hash(), nil
+	}
+	hash, err := cc.gitRepo.ResolveRevision(plumbing.Revision(cc.revision))
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("couldn't resolve revision '%s': %w", cc.revision, err)
+	}
+	return *hash, nil
+}
+
+// MainBranch returns the default branch of the repository
+func (cc *ConventionalCommits) MainBranch() (string, error) {
+	if cc.mainBranch == "" {
+		mainBranch, source, err := cc.getMainBranch()
+		if err != nil {
+			return "", fmt.Errorf("couldn't figure out main branch: %w", err)
+		}
+		cc.mainBranch, cc.mainSource = mainBranch, source
+	}
+	return cc.mainBranch, nil
+}
+
+// MainBranchSource returns where the main branch was resolved from: flag, gh
+// or origin/HEAD
+func (cc *ConventionalCommits) MainBranchSource() (string, error) {
+	if _, err := cc.MainBranch(); err != nil {
+		return "", err
+	}
+	return cc.mainSource, nil
+}
+
+// HeadBranch returns the name of the branch that is checked out. GitHub
+// Actions checks out a detached HEAD, so the branch of the event takes
+// precedence: GITHUB_HEAD_REF of a pull request, then GITHUB_REF_NAME when it
+// is a branch.
+func (cc *ConventionalCommits) HeadBranch() (string, error) {
+	if headRef := os.Getenv("GITHUB_HEAD_REF"); headRef != "" {
+		return headRef, nil
+	}
+	if refName := os.Getenv("GITHUB_REF_NAME"); refName != "" && os.Getenv("GITHUB_REF_TYPE") == "branch" {
+		return refName, nil
+	}
+	head, err := cc.gitRepo.Head()
+	if err != nil {
+		return "", fmt.Errorf("couldn't get head: %w", err)
+	}
+	return head.Name().Short(), nil
+}
+
+func (cc *ConventionalCommits) getMainBranch() (string, string, error) {
 	args := []string{"repo", "view", "--json", "defaultBranchRef", "--jq", ".defaultBranchRef.name"}
 	stdOut, _, err := gh.Exec(args...)
 	if err != nil {
-		return "", err
+		// without GitHub, the remote HEAD tells the default branch
+		remoteHead, refErr := cc.gitRepo.Reference(plumbing.NewRemoteHEADReferenceName("origin"), false)
+		if refErr != nil || remoteHead.Type() != plumbing.SymbolicReference {
+			return "", "", err
+		}
+		cc.warnf("couldn't get the default branch from GitHub, using origin/HEAD: %v", err)
+		return strings.TrimPrefix(remoteHead.Target().Short(), "origin/"), "origin/HEAD", nil
 	}
 
-	return strings.TrimSpace(stdOut.String()), nil
+	return strings.TrimSpace(stdOut.String()), "gh", nil
 }
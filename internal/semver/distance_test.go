@@ -0,0 +1,456 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package semver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestCommitDistance(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(r *testRepo)
+		want  uint64
+	}{
+		{
+			name: "tagged HEAD",
+			build: func(r *testRepo) {
+				r.commit("fix: a")
+				r.tag("v1.0.0")
+			},
+			want: 0,
+		},
+		{
+			name: "commit right after the tag",
+			build: func(r *testRepo) {
+				r.commit("fix: a")
+				r.tag("v1.0.0")
+				r.commit("fix: b")
+			},
+			want: 1,
+		},
+		{
+			name: "linear history",
+			build: func(r *testRepo) {
+				r.commit("fix: a")
+				r.tag("v1.0.0")
+				r.commit("fix: b")
+				r.commit("fix: c")
+				r.commit("fix: d")
+			},
+			want: 3,
+		},
+		{
+			name: "untagged history",
+			build: func(r *testRepo) {
+				r.commit("fix: a")
+				r.commit("fix: b")
+			},
+			want: 2,
+		},
+		{
+			name: "merge of a branch forked before the tag",
+			build: func(r *testRepo) {
+				r.commit("chore: init")
+				r.commit("fix: a")
+				r.checkout("feature")
+				r.commit("feat: side")
+				r.checkout("main")
+				r.commit("fix: b")
+				r.tag("v1.0.0")
+				r.commit("fix: c")
+				r.merge("feature", "Merge branch 'feature'")
+			},
+			// fix: c, feat: side and the merge
+			want: 3,
+		},
+		{
+			name: "merge of a branch forked before the tag and merged into it",
+			build: func(r *testRepo) {
+				r.commit("chore: init")
+				r.checkout("feature")
+				r.commit("feat: side")
+				r.commit("feat: more")
+				r.checkout("main")
+				r.commit("fix: a")
+				r.merge("feature", "Merge branch 'feature'")
+				r.tag("v1.0.0")
+				r.commit("fix: b")
+				r.merge("feature", "Merge branch 'feature' again")
+			},
+			// fix: b and the second merge
+			want: 2,
+		},
+		{
+			name: "tags on both sides of a merge",
+			build: func(r *testRepo) {
+				r.commit("chore: init")
+				r.checkout("feature")
+				r.commit("feat: side")
+				r.tag("v0.9.0")
+				r.commit("feat: more")
+				r.checkout("main")
+				r.commit("fix: a")
+				r.tag("v1.0.0")
+				r.merge("feature", "Merge branch 'feature'")
+			},
+			// feat: more and the merge
+			want: 2,
+		},
+		{
+			name: "HEAD behind a newer tag",
+			build: func(r *testRepo) {
+				r.commit("fix: a")
+				r.tag("v1.0.0")
+				r.commit("fix: b")
+				r.checkout("feature")
+				r.checkout("main")
+				r.commit("fix: c")
+				r.tag("v1.0.1")
+				r.checkout("feature")
+			},
+			// fix: b, which the newer tag descends from
+			want: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			test.build(r)
+			cc := NewConventionalCommits(r.repo, options())
+			got, err := cc.commitDistance(r.head(), r.tagRefs())
+			if err != nil {
+				t.Fatalf("couldn't determine commit distance: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("commitDistance() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCommitDistanceInVersion(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("chore: init")
+	r.checkout("feature")
+	r.commit("feat: side")
+	r.checkout("main")
+	r.commit("fix: a")
+	r.tag("v1.0.0")
+	r.commit("fix: b")
+	r.merge("feature", "Merge branch 'feature'")
+	r.checkout("next")
+	head := r.head()
+
+	if got, want := r.version(options()), "v1.1.0-next.3."+short(head); got != want {
+		t.Errorf("SemVer() = %s, want %s", got, want)
+	}
+}
+
+func TestBuildNumberLinear(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("chore: init")
+	r.tag("v1.0.0")
+	for i := 0; i < 9; i++ {
+		r.commit("fix: a")
+	}
+
+	got, err := NewConventionalCommits(r.repo, options()).BuildNumber()
+	if err != nil {
+		t.Fatalf("couldn't determine build number: %v", err)
+	}
+	// all commits count, regardless of the tags
+	if got != 10 {
+		t.Errorf("BuildNumber() = %d, want 10", got)
+	}
+}
+
+func TestBuildNumber(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("chore: init")
+	r.checkout("feature")
+	r.commit("feat: side")
+	r.checkout("main")
+	r.commit("fix: a")
+	r.tag("v1.0.0")
+	r.merge("feature", "Merge branch 'feature'")
+
+	got, err := NewConventionalCommits(r.repo, options()).BuildNumber()
+	if err != nil {
+		t.Fatalf("couldn't determine build number: %v", err)
+	}
+	if got != 4 {
+		t.Errorf("BuildNumber() = %d, want 4", got)
+	}
+}
+
+func TestCommitDistanceAtTagBoundary(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(r *testRepo)
+		want  func(r *testRepo) string
+	}{
+		{
+			name: "lightweight tag on HEAD",
+			build: func(r *testRepo) {
+				r.commit("fix: a")
+				r.tag("v1.0.0")
+			},
+			want: func(r *testRepo) string { return "v1.0.0" },
+		},
+		{
+			name: "annotated tag on HEAD",
+			build: func(r *testRepo) {
+				r.commit("fix: a")
+				r.annotate("v1.0.0")
+			},
+			want: func(r *testRepo) string { return "v1.0.0" },
+		},
+		{
+			name: "commit right after a lightweight tag",
+			build: func(r *testRepo) {
+				r.commit("fix: a")
+				r.tag("v1.0.0")
+				r.commit("fix: b")
+			},
+			want: func(r *testRepo) string { return "v1.0.1-feature.1." + short(r.head()) },
+		},
+		{
+			name: "commit right after an annotated tag",
+			build: func(r *testRepo) {
+				r.commit("fix: a")
+				r.annotate("v1.0.0")
+				r.commit("fix: b")
+			},
+			want: func(r *testRepo) string { return "v1.0.1-feature.1." + short(r.head()) },
+		},
+		{
+			name: "second commit after the tag",
+			build: func(r *testRepo) {
+				r.commit("fix: a")
+				r.tag("v1.0.0")
+				r.commit("fix: b")
+				r.commit("fix: c")
+			},
+			want: func(r *testRepo) string { return "v1.0.1-feature.2." + short(r.head()) },
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.checkout("feature")
+			test.build(r)
+			if got, want := r.version(options()), test.want(r); got != want {
+				t.Errorf("SemVer() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestStableDistance(t *testing.T) {
+	tests := []struct {
+		stable   bool
+		distance string
+	}{
+		{stable: false, distance: "1"},
+		{stable: true, distance: "3"},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.stable), func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("chore: init")
+			r.tag("v1.0.0")
+			r.commit("feat: a")
+			r.tag("v1.1.0-rc.1")
+			r.commit("fix: b")
+			r.tag("v1.1.0-rc.2")
+			r.checkout("next")
+			head := r.commit("fix: c")
+
+			opts := options()
+			opts.StableDistance = test.stable
+			want := "v1.1.1-next." + test.distance + "." + short(head)
+			if got := r.version(opts); got != want {
+				t.Errorf("SemVer() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestTaggedHeadOnBranch(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("feat: a")
+	r.tag("v1.0.0")
+	r.checkout("feature")
+	r.commit("feat: b")
+	r.tag("v1.1.0-beta.1")
+
+	if got := r.version(options()); got != "v1.1.0-beta.1" {
+		t.Errorf("SemVer() = %s, want the tag v1.1.0-beta.1", got)
+	}
+}
+
+func TestAmendedHead(t *testing.T) {
+	r := newTestRepo(t)
+	base := r.commit("feat: a")
+	r.tag("v1.0.0")
+	r.checkout("feature")
+	first := r.commit("fix: b", "CHANGELOG.md")
+	if got, want := r.version(options()), "v1.0.1-feature.1."+short(first); got != want {
+		t.Fatalf("SemVer() = %s, want %s", got, want)
+	}
+
+	// replacing HEAD keeps the distance, the hash is the one of the new HEAD
+	amended := r.commitWith("fix: b, amended", []plumbing.Hash{base}, []string{"CHANGELOG.md"})
+	if got, want := r.version(options()), "v1.0.1-feature.1."+short(amended); got != want {
+		t.Errorf("SemVer() = %s after amending, want %s", got, want)
+	}
+}
+
+func TestNoBumpExtended(t *testing.T) {
+	tests := []struct {
+		name     string
+		build    func(r *testRepo)
+		want     string
+		distance string
+	}{
+		{
+			name: "chores on main",
+			build: func(r *testRepo) {
+				r.commit("chore: b")
+				r.commit("docs: c")
+			},
+			want: "v1.0.0",
+		},
+		{
+			name: "chores on a branch",
+			build: func(r *testRepo) {
+				r.checkout("feature")
+				r.commit("chore: b")
+				r.commit("docs: c")
+			},
+			want:     "v1.0.0",
+			distance: "2",
+		},
+		{
+			name: "chores on a branch past a prerelease tag",
+			build: func(r *testRepo) {
+				r.checkout("feature")
+				r.commit("fix: b")
+				r.tag("v1.0.1-feature.1.0000000")
+				r.commit("chore: c")
+			},
+			want:     "v1.0.1",
+			distance: "1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("feat: a")
+			r.tag("v1.0.0")
+			test.build(r)
+			want := test.want
+			if test.distance != "" {
+				want += "-feature." + test.distance + "." + short(r.head())
+			}
+			// the extended information is the one of HEAD, not of the tag
+			if got := r.version(options()); got != want {
+				t.Errorf("SemVer() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+// TestEqualTagsExtended checks the extended information when both traversals
+// find versions that are equal, which comes from HEAD whichever tag wins
+func TestEqualTagsExtended(t *testing.T) {
+	tests := []struct {
+		name     string
+		branch   string
+		build    func(r *testRepo)
+		want     string
+		distance string
+	}{
+		{
+			name:   "same tag on main",
+			branch: "main",
+			build: func(r *testRepo) {
+				r.commit("fix: b")
+			},
+			want: "v1.0.1",
+		},
+		{
+			name:   "same tag on a branch",
+			branch: "feature",
+			build: func(r *testRepo) {
+				r.commit("fix: b")
+			},
+			want:     "v1.0.1",
+			distance: "1",
+		},
+		{
+			name:   "same tag at HEAD of a branch",
+			branch: "feature",
+			build:  func(r *testRepo) {},
+			want:   "v1.0.0",
+		},
+		{
+			name:   "equal tags merged on main",
+			branch: "main",
+			build:  equalTags,
+			want:   "v1.1.0",
+		},
+		{
+			name:   "equal tags merged on a branch",
+			branch: "feature",
+			build:  equalTags,
+			want:   "v1.1.0",
+			// the merge, past both tags
+			distance: "1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("feat: a")
+			r.tag("v1.0.0")
+			r.checkout(test.branch)
+			test.build(r)
+			want := test.want
+			if test.distance != "" {
+				want += "-feature." + test.distance + "." + short(r.head())
+			}
+			if got := r.version(options()); got != want {
+				t.Errorf("SemVer() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+// equalTags tags 1.1.0 on a side branch and v1.1.0 on the checked out branch,
+// then merges the side branch
+func equalTags(r *testRepo) {
+	branch := r.branch
+	r.checkout("side")
+	r.commit("feat: b")
+	r.tag("1.1.0")
+	r.checkout(branch)
+	r.commit("feat: c")
+	r.tag("v1.1.0")
+	r.merge("side", "Merge branch 'side'")
+}
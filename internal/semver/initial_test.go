@@ -0,0 +1,106 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package semver
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestInitialVersionLog(t *testing.T) {
+	tests := []struct {
+		name   string
+		tags   []string
+		prefix string
+		want   string
+	}{
+		{name: "no tags", want: "no tags found in the repository"},
+		{name: "prefix mismatch", tags: []string{"v1.0.0", "web-v1.0.0"}, prefix: "api", want: "none of the 2 tags match prefix 'api'"},
+		{name: "unparseable tags", tags: []string{"latest"}, want: "none of the 1 tags could be parsed as a version"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("feat: a")
+			for _, tag := range test.tags {
+				r.tag(tag)
+			}
+
+			var buf bytes.Buffer
+			opts := options()
+			opts.Prefix = test.prefix
+			opts.Logger = log.New(&buf, "", 0)
+			r.version(opts)
+			if !strings.Contains(buf.String(), test.want) {
+				t.Errorf("SemVer() logged %q, want %q", buf.String(), test.want)
+			}
+		})
+	}
+}
+
+func TestInitialVersionPrefix(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{name: "bare", opts: Options{}, want: "0.1.0"},
+		{name: "leading v", opts: Options{LeadingV: "v"}, want: "v0.1.0"},
+		{name: "prefix", opts: Options{Prefix: "api", LeadingV: "v"}, want: "api-v0.1.0"},
+		{name: "prefix with slash", opts: Options{Prefix: "api/", LeadingV: "v"}, want: "api/v0.1.0"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("feat: a")
+			opts := test.opts
+			opts.MainBranch = "main"
+			version, err := NewConventionalCommits(r.repo, opts).SemVer()
+			if err != nil {
+				t.Fatalf("couldn't calculate version: %v", err)
+			}
+			if got := version.PrintTag(true); got != test.want {
+				t.Errorf("PrintTag() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+// TestInitialVersionUntagged checks the initial version of library callers,
+// which the leading v of the commandline doesn't apply to
+func TestInitialVersionUntagged(t *testing.T) {
+	tests := []struct {
+		name     string
+		leadingV string
+		want     string
+	}{
+		{name: "zero value", want: "0.1.0"},
+		{name: "leading v", leadingV: "v", want: "v0.1.0"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("feat: a")
+			version, err := NewConventionalCommits(r.repo, Options{MainBranch: "main", LeadingV: test.leadingV}).SemVer()
+			if err != nil {
+				t.Fatalf("SemVer() error: %v", err)
+			}
+			if got := version.String(); got != test.want {
+				t.Errorf("SemVer() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package semver
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseSemVerPrefix(t *testing.T) {
+	tests := []struct {
+		input      string
+		prefix     string
+		core       string
+		prerelease string
+	}{
+		{input: "v1.2.3", core: "1.2.3"},
+		{input: "api-v1.2.3", prefix: "api", core: "1.2.3"},
+		{input: "api/v1.2.3", prefix: "api/", core: "1.2.3"},
+		{input: "services/api/v1.2.3", prefix: "services/api/", core: "1.2.3"},
+		{input: "my-api-1.2.3", prefix: "my-api", core: "1.2.3"},
+		{input: "release-2024-1.2.3", prefix: "release-2024", core: "1.2.3"},
+		{input: "node-18-1.2.3", prefix: "node-18", core: "1.2.3"},
+		{input: "node-18-1.2.3-rc-1", prefix: "node-18", core: "1.2.3", prerelease: "rc-1"},
+		{input: "builds/2024/v1.2", prefix: "builds/2024/", core: "1.2.0"},
+		{input: "v1.0.0-rc-1", core: "1.0.0", prerelease: "rc-1"},
+		{input: "api/v1.0.0-rc-1", prefix: "api/", core: "1.0.0", prerelease: "rc-1"},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			for _, strict := range []bool{false, true} {
+				got, err := ParseSemVerWithOptions(test.input, ParseOptions{Strict: strict})
+				if err != nil {
+					t.Fatalf("ParseSemVerWithOptions() strict %t error: %v", strict, err)
+				}
+				if got.Prefix != test.prefix || got.Core() != test.core || got.Prerelease != test.prerelease {
+					t.Errorf("ParseSemVerWithOptions() strict %t = prefix %q core %s prerelease %q, want prefix %q core %s prerelease %q",
+						strict, got.Prefix, got.Core(), got.Prerelease, test.prefix, test.core, test.prerelease)
+				}
+			}
+		})
+	}
+}
+
+func TestIsolatePrefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		want   string
+	}{
+		{name: "dash", prefix: "api", want: "1.2.4"},
+		{name: "slash", prefix: "web", want: "0.4.1"},
+		{name: "slash given", prefix: "web/", want: "0.4.1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("fix: a")
+			r.tag("v1.5.0")
+			r.tag("api-v1.2.3")
+			r.tag("web/v0.4.0")
+			r.tag("web/api-v3.0.0")
+			r.commit("fix: b")
+
+			opts := options()
+			opts.Prefix = test.prefix
+			opts.IsolatePrefix = true
+			version, err := NewConventionalCommits(r.repo, opts).SemVer()
+			if err != nil {
+				t.Fatalf("couldn't calculate version: %v", err)
+			}
+			if got := version.Core(); got != test.want {
+				t.Errorf("SemVer() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTagPrefix(t *testing.T) {
+	tests := []struct {
+		name  string
+		tags  []string
+		opts  Options
+		want  string
+		other string
+	}{
+		{name: "dash", tags: []string{"api-v1.2.3"}, opts: Options{Prefix: "api"}, want: "api-v1.2.4"},
+		{name: "slash", tags: []string{"api/v1.2.3"}, opts: Options{Prefix: "api"}, want: "api/v1.2.4"},
+		{name: "slash given", tags: []string{"api/v1.2.3"}, opts: Options{Prefix: "api/"}, want: "api/v1.2.4"},
+		{name: "similar module", tags: []string{"api-v1.2.3", "apiserver-v2.0.0"}, opts: Options{Prefix: "api"}, want: "api-v1.2.4"},
+		{name: "similar module given", tags: []string{"api-v1.2.3", "apiserver-v2.0.0"}, opts: Options{Prefix: "apiserver"}, want: "apiserver-v2.0.1"},
+		{name: "initial slash", opts: Options{Prefix: "api/", LeadingV: "v"}, want: "api/v0.1.0"},
+		{name: "initial", opts: Options{Prefix: "api", LeadingV: "v"}, want: "api-v0.1.0"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("chore: init")
+			for _, name := range test.tags {
+				r.tag(name)
+			}
+			r.commit("fix: a")
+
+			opts := test.opts
+			opts.MainBranch = "main"
+			version, err := NewConventionalCommits(r.repo, opts).SemVer()
+			if err != nil {
+				t.Fatalf("couldn't calculate version: %v", err)
+			}
+			// the flag replaces the prefix, as in the output of main
+			version.SetPrefix(test.opts.Prefix)
+			if got := version.String(); got != test.want {
+				t.Errorf("SemVer() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSetPrefix(t *testing.T) {
+	tests := []struct {
+		parsed string
+		prefix string
+		want   string
+	}{
+		{parsed: "api/v1.2.3", prefix: "api", want: "api/v1.2.3"},
+		{parsed: "api/v1.2.3", prefix: "api-", want: "api-v1.2.3"},
+		{parsed: "api-v1.2.3", prefix: "api/", want: "api/v1.2.3"},
+		{parsed: "api-v1.2.3", prefix: "web", want: "web-v1.2.3"},
+		{parsed: "v1.2.3", prefix: "web/", want: "web/v1.2.3"},
+		{parsed: "api/v1.2.3", prefix: "", want: "v1.2.3"},
+	}
+	for _, test := range tests {
+		t.Run(test.parsed+" "+test.prefix, func(t *testing.T) {
+			version, err := ParseSemVer(test.parsed)
+			if err != nil {
+				t.Fatalf("ParseSemVer() error: %v", err)
+			}
+			version.SetPrefix(test.prefix)
+			if got := version.String(); got != test.want {
+				t.Errorf("SetPrefix() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPrintTagPrefixAndLeadingV(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		leadingV string
+		want     string
+	}{
+		{prefix: "", leadingV: "", want: "1.2.3"},
+		{prefix: "", leadingV: "v", want: "v1.2.3"},
+		{prefix: "api", leadingV: "", want: "api-1.2.3"},
+		{prefix: "api", leadingV: "v", want: "api-v1.2.3"},
+		{prefix: "api-", leadingV: "", want: "api-1.2.3"},
+		{prefix: "api-", leadingV: "v", want: "api-v1.2.3"},
+		{prefix: "api/", leadingV: "", want: "api/1.2.3"},
+		{prefix: "api/", leadingV: "v", want: "api/v1.2.3"},
+	}
+	for _, test := range tests {
+		t.Run(test.want, func(t *testing.T) {
+			version := NewSemVer(1, 2, 3)
+			version.Prefix = test.prefix
+			version.LeadingV = test.leadingV
+			if got := version.PrintTag(true); got != test.want {
+				t.Errorf("PrintTag() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPrefixOnRelease(t *testing.T) {
+	tests := []struct {
+		prefixOnRelease bool
+		release         string
+		prerelease      string
+	}{
+		{prefixOnRelease: false, release: "api-v1.2.3", prerelease: "api-v1.2.3-main.4.63ee8c4"},
+		{prefixOnRelease: true, release: "api-v1.2.3", prerelease: "v1.2.3-main.4.63ee8c4"},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.prefixOnRelease), func(t *testing.T) {
+			version := NewSemVer(1, 2, 3)
+			version.Prefix = "api"
+			version.LeadingV = "v"
+			version.PrefixOnRelease = test.prefixOnRelease
+			version.SetBranch("main")
+			version.SetCommitDistance(4)
+			version.SetCommitHash("63ee8c4")
+			if got := version.PrintTag(true); got != test.release {
+				t.Errorf("PrintTag(true) = %s, want %s", got, test.release)
+			}
+			if got := version.PrintTag(false); got != test.prerelease {
+				t.Errorf("PrintTag(false) = %s, want %s", got, test.prerelease)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package semver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// listRemoteTags maps the tags on the remote to the commits they point at
+func (cc *ConventionalCommits) listRemoteTags() (map[string]plumbing.Hash, error) {
+	remote, err := cc.gitRepo.Remote(cc.remoteTags)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get remote '%s': %w", cc.remoteTags, err)
+	}
+	refs, err := remote.List(&git.ListOptions{
+		Auth:          cc.remoteAuth,
+		PeelingOption: git.AppendPeeled,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't list remote '%s': %w", cc.remoteTags, err)
+	}
+
+	tags := map[string]plumbing.Hash{}
+	for _, ref := range refs {
+		if !ref.Name().IsTag() {
+			continue
+		}
+		// annotated tags are peeled to the commit they point at
+		name := ref.Name().Short()
+		if peeled := strings.TrimSuffix(name, "^{}"); peeled != name {
+			tags[peeled] = ref.Hash()
+		} else if _, ok := tags[name]; !ok {
+			tags[name] = ref.Hash()
+		}
+	}
+	return tags, nil
+}
+
+func sortedNames(tags map[string]plumbing.Hash) []string {
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
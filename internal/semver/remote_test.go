@@ -0,0 +1,93 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package semver
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// remoteURL is served from the in-memory storages of remoteRepos
+const remoteURL = "mem://example.com/repo.git"
+
+var remoteRepos = server.MapLoader{}
+
+func init() {
+	client.InstallProtocol("mem", server.NewServer(remoteRepos))
+}
+
+// remote serves the commits of the repository as the origin remote, with
+// tags of its own
+func (r *testRepo) remote(tags map[string]plumbing.Hash) {
+	r.t.Helper()
+	storage := memory.NewStorage()
+	objects, err := r.repo.Storer.IterEncodedObjects(plumbing.AnyObject)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	err = objects.ForEach(func(obj plumbing.EncodedObject) error {
+		_, err := storage.SetEncodedObject(obj)
+		return err
+	})
+	if err != nil {
+		r.t.Fatalf("couldn't copy objects: %v", err)
+	}
+	storage.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("main"), r.head()))
+	storage.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName("main")))
+	for name, hash := range tags {
+		storage.SetReference(plumbing.NewHashReference(plumbing.NewTagReferenceName(name), hash))
+	}
+	remoteRepos[remoteURL] = storage
+	r.t.Cleanup(func() { delete(remoteRepos, remoteURL) })
+
+	if _, err := r.repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remoteURL}}); err != nil {
+		r.t.Fatalf("couldn't create remote: %v", err)
+	}
+}
+
+func TestRemoteTags(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteTags string
+		want       string
+	}{
+		{name: "local tags only", want: "v1.1.0"},
+		{name: "remote tags", remoteTags: "origin", want: "v1.1.1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("chore: init")
+			r.tag("v1.0.0")
+			released := r.commit("feat: b")
+			r.commit("fix: c")
+			r.remote(map[string]plumbing.Hash{
+				"v1.1.0": released,
+				// a tag of commits that weren't fetched is skipped
+				"v3.0.0": plumbing.NewHash("1234567890123456789012345678901234567890"),
+			})
+
+			opts := options()
+			opts.RemoteTags = test.remoteTags
+			if got := r.version(opts); got != test.want {
+				t.Errorf("SemVer() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
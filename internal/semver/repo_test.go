@@ -0,0 +1,255 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package semver
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// testRepo builds a history in an in-memory repository, commit by commit
+type testRepo struct {
+	t      testing.TB
+	repo   *git.Repository
+	branch string
+	when   time.Time
+	// files are the contents of the tree of each commit
+	files map[plumbing.Hash]map[string]string
+}
+
+func newTestRepo(t testing.TB) *testRepo {
+	t.Helper()
+	repo, err := git.Init(memory.NewStorage(), memfs.New())
+	if err != nil {
+		t.Fatalf("couldn't init repository: %v", err)
+	}
+	r := &testRepo{
+		t:     t,
+		repo:  repo,
+		when:  time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		files: map[plumbing.Hash]map[string]string{},
+	}
+	r.checkout("main")
+	// the GitHub environment of the test run would decide the branch
+	for _, name := range []string{"GITHUB_HEAD_REF", "GITHUB_REF_NAME", "GITHUB_REF_TYPE"} {
+		t.Setenv(name, "")
+	}
+	return r
+}
+
+// head returns the commit the checked out branch points at, zero before the
+// first commit
+func (r *testRepo) head() plumbing.Hash {
+	ref, err := r.repo.Reference(plumbing.NewBranchReferenceName(r.branch), true)
+	if err != nil {
+		return plumbing.ZeroHash
+	}
+	return ref.Hash()
+}
+
+// checkout switches to the branch, creating it at HEAD when missing
+func (r *testRepo) checkout(branch string) {
+	r.t.Helper()
+	head := r.head()
+	r.branch = branch
+	name := plumbing.NewBranchReferenceName(branch)
+	if _, err := r.repo.Reference(name, true); err != nil && !head.IsZero() {
+		r.setRef(plumbing.NewHashReference(name, head))
+	}
+	r.setRef(plumbing.NewSymbolicReference(plumbing.HEAD, name))
+}
+
+// detach points HEAD at the commit
+func (r *testRepo) detach(hash plumbing.Hash) {
+	r.setRef(plumbing.NewHashReference(plumbing.HEAD, hash))
+}
+
+func (r *testRepo) setRef(ref *plumbing.Reference) {
+	r.t.Helper()
+	if err := r.repo.Storer.SetReference(ref); err != nil {
+		r.t.Fatalf("couldn't set reference %s: %v", ref.Name(), err)
+	}
+}
+
+// commit commits the message on the checked out branch, changing the files
+func (r *testRepo) commit(message string, files ...string) plumbing.Hash {
+	r.t.Helper()
+	var parents []plumbing.Hash
+	if head := r.head(); !head.IsZero() {
+		parents = append(parents, head)
+	}
+	return r.commitWith(message, parents, files)
+}
+
+// merge commits a merge of the branch into the checked out branch
+func (r *testRepo) merge(branch, message string) plumbing.Hash {
+	r.t.Helper()
+	ref, err := r.repo.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		r.t.Fatalf("couldn't find branch %s: %v", branch, err)
+	}
+	return r.commitWith(message, []plumbing.Hash{r.head(), ref.Hash()}, nil)
+}
+
+func (r *testRepo) commitWith(message string, parents []plumbing.Hash, files []string) plumbing.Hash {
+	r.t.Helper()
+	contents := map[string]string{}
+	for _, parent := range parents {
+		for name, content := range r.files[parent] {
+			contents[name] = content
+		}
+	}
+	for _, name := range files {
+		contents[name] = message
+	}
+	if len(files) == 0 && len(parents) < 2 {
+		// every commit changes something, by default a file of its own
+		contents[path.Join("changes", r.when.Format("150405"))] = message
+	}
+
+	r.when = r.when.Add(time.Minute)
+	signature := object.Signature{Name: "Test", Email: "test@example.com", When: r.when}
+	commit := &object.Commit{
+		Author:       signature,
+		Committer:    signature,
+		Message:      message,
+		TreeHash:     r.writeTree(contents),
+		ParentHashes: parents,
+	}
+	hash := r.store(commit)
+	r.files[hash] = contents
+	r.setRef(plumbing.NewHashReference(plumbing.NewBranchReferenceName(r.branch), hash))
+	return hash
+}
+
+// writeTree stores the nested trees of the files and returns the root
+func (r *testRepo) writeTree(files map[string]string) plumbing.Hash {
+	r.t.Helper()
+	tree := &object.Tree{}
+	dirs := map[string]map[string]string{}
+	for name, content := range files {
+		if dir, rest, found := strings.Cut(name, "/"); found {
+			if dirs[dir] == nil {
+				dirs[dir] = map[string]string{}
+			}
+			dirs[dir][rest] = content
+			continue
+		}
+		blob := r.repo.Storer.NewEncodedObject()
+		blob.SetType(plumbing.BlobObject)
+		writer, _ := blob.Writer()
+		writer.Write([]byte(content))
+		writer.Close()
+		hash, err := r.repo.Storer.SetEncodedObject(blob)
+		if err != nil {
+			r.t.Fatalf("couldn't store blob: %v", err)
+		}
+		tree.Entries = append(tree.Entries, object.TreeEntry{Name: name, Mode: filemode.Regular, Hash: hash})
+	}
+	for dir, dirFiles := range dirs {
+		tree.Entries = append(tree.Entries, object.TreeEntry{Name: dir, Mode: filemode.Dir, Hash: r.writeTree(dirFiles)})
+	}
+	sort.Slice(tree.Entries, func(i, j int) bool { return tree.Entries[i].Name < tree.Entries[j].Name })
+	return r.store(tree)
+}
+
+// store encodes the object into the repository
+func (r *testRepo) store(value interface {
+	Encode(plumbing.EncodedObject) error
+}) plumbing.Hash {
+	r.t.Helper()
+	obj := r.repo.Storer.NewEncodedObject()
+	if err := value.Encode(obj); err != nil {
+		r.t.Fatalf("couldn't encode object: %v", err)
+	}
+	hash, err := r.repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		r.t.Fatalf("couldn't store object: %v", err)
+	}
+	return hash
+}
+
+// tag puts a lightweight tag on HEAD
+func (r *testRepo) tag(name string) {
+	r.tagAt(name, r.head())
+}
+
+// tagAt puts a lightweight tag on the commit
+func (r *testRepo) tagAt(name string, hash plumbing.Hash) {
+	r.setRef(plumbing.NewHashReference(plumbing.NewTagReferenceName(name), hash))
+}
+
+// annotate puts an annotated tag on HEAD
+func (r *testRepo) annotate(name string) {
+	r.t.Helper()
+	r.when = r.when.Add(time.Minute)
+	_, err := r.repo.CreateTag(name, r.head(), &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "Test", Email: "test@example.com", When: r.when},
+		Message: name,
+	})
+	if err != nil {
+		r.t.Fatalf("couldn't create tag %s: %v", name, err)
+	}
+}
+
+// tagRefs maps the tagged commits to their tags
+func (r *testRepo) tagRefs() map[string]string {
+	r.t.Helper()
+	tags, err := r.repo.Tags()
+	if err != nil {
+		r.t.Fatalf("couldn't list tags: %v", err)
+	}
+	tagRefs := map[string]string{}
+	cc := NewConventionalCommits(r.repo, options())
+	err = tags.ForEach(func(ref *plumbing.Reference) error {
+		hash, err := cc.tagTarget(ref)
+		tagRefs[hash.String()] = ref.Name().Short()
+		return err
+	})
+	if err != nil {
+		r.t.Fatalf("couldn't resolve tags: %v", err)
+	}
+	return tagRefs
+}
+
+// options are the options of the tests, with the main branch given so GitHub
+// isn't asked
+func options() Options {
+	return Options{MainBranch: "main"}
+}
+
+// version calculates the version with the options, failing the test on errors
+func (r *testRepo) version(opts Options) string {
+	r.t.Helper()
+	version, err := NewConventionalCommits(r.repo, opts).SemVer()
+	if err != nil {
+		r.t.Fatalf("couldn't calculate version: %v", err)
+	}
+	return version.String()
+}
+
+// short returns the abbreviated commit hash of the extended information
+func short(hash plumbing.Hash) string {
+	return hash.String()[:7]
+}
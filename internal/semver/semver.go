@@ -21,12 +21,21 @@ import (
 )
 
 type SemVer struct {
-	Prefix   string
-	LeadingV string
-	Major    uint64
-	Minor    uint64
-	Patch    uint64
-	Ext      *SemVerExtended
+	Prefix     string
+	LeadingV   string
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Ext        *SemVerExtended
+	Prerelease string
+	Metadata   string
+	Separator  string
+	ShortTag   bool
+
+	// PrefixOnRelease omits the prefix from prerelease versions
+	PrefixOnRelease bool
+	// Padding zero-pads major, minor and patch to this width, like 001.002.003
+	Padding int
 }
 
 type SemVerExtended struct {
@@ -35,67 +44,273 @@ type SemVerExtended struct {
 	CommitHash     string
 }
 
+// ParseOptions configures how a version is parsed
+type ParseOptions struct {
+	// Separator between the extended branch, commit distance and commit hash
+	Separator string
+	// Pattern replaces the default regular expression, see CompilePattern
+	Pattern *regexp.Regexp
+	// Strict requires the whole input to be the version
+	Strict bool
+	// PlusPrerelease takes the + segment of legacy tags like 1.2.3+rc1 as
+	// prerelease instead of build metadata
+	PlusPrerelease bool
+}
+
+// DefaultSeparator is the separator between the extended fields
+const DefaultSeparator = "."
+
 var branchStripCharacters = regexp.MustCompile(`[^0-9A-Za-z-]`)
 
+// branchIdentifier turns the branch into a valid prerelease identifier,
+// keeping alphanumerics and hyphens
+func branchIdentifier(branch string) string {
+	identifier := branchStripCharacters.ReplaceAllString(branch, "")
+	if identifier == "" {
+		return "branch"
+	}
+	// numeric identifiers must not have leading zeros
+	if strings.Trim(identifier, "0123456789") == "" {
+		if identifier = strings.TrimLeft(identifier, "0"); identifier == "" {
+			identifier = "0"
+		}
+	}
+	return identifier
+}
+
+// hashIdentifier turns the commit hash into a valid prerelease identifier, a
+// numeric hash with a leading zero gets a g like git describe puts in front
+func hashIdentifier(hash string) string {
+	if strings.HasPrefix(hash, "0") && len(hash) > 1 && strings.Trim(hash, "0123456789") == "" {
+		return "g" + hash
+	}
+	return hash
+}
+
+// strictGrammar is the regular expression suggested by the SemVer 2.0.0 spec
+var strictGrammar = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// IsStrict tells whether the version is valid SemVer 2.0.0, without prefix or
+// leading v
+func IsStrict(version string) bool {
+	return strictGrammar.MatchString(version)
+}
+
 func NewSemVer(major, minor, patch uint64) *SemVer {
 	return &SemVer{
-		Prefix:   "",
-		LeadingV: "v",
-		Major:    major,
-		Minor:    minor,
-		Patch:    patch,
-		Ext:      nil,
+		Prefix:    "",
+		LeadingV:  "v",
+		Major:     major,
+		Minor:     minor,
+		Patch:     patch,
+		Ext:       nil,
+		Separator: DefaultSeparator,
 	}
 }
 
-func ParseSemVer(input string) (*SemVer, error) {
-	re, err := regexp.Compile(`(?P<prefix>.+-)?(?P<v>v)?(?P<major>\d+)\.(?P<minor>\d+)\.(?P<patch>\d+)(?P<extended>-(?P<branch>\w+)\.(?P<commit_distance>\d+)\.(?P<commit_hash>\w+))?`)
+// CompilePattern compiles a custom regular expression to parse versions, it
+// requires the named groups major, minor and patch
+func CompilePattern(expr string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(expr)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("couldn't compile version pattern: %w", err)
+	}
+	for _, name := range []string{"major", "minor", "patch"} {
+		if re.SubexpIndex(name) < 0 {
+			return nil, fmt.Errorf("version pattern '%s' lacks the named group '%s'", expr, name)
+		}
+	}
+	return re, nil
+}
+
+func ParseSemVer(input string) (*SemVer, error) {
+	return ParseSemVerWithOptions(input, ParseOptions{})
+}
+
+func ParseSemVerWithOptions(input string, opts ParseOptions) (*SemVer, error) {
+	// versions from shell pipelines may be padded or quoted
+	input = strings.TrimSpace(strings.Trim(strings.TrimSpace(input), `"'`))
+	separator := opts.Separator
+	if separator == "" {
+		separator = DefaultSeparator
+	}
+	sep := regexp.QuoteMeta(separator)
+	// minor and patch are optional for short tags like v1 and v1.2, which
+	// need the leading v so numbers like build-42 aren't taken for a version
+	core := `(?P<major>\d+)(?:\.(?P<minor>\d+)(?:\.(?P<patch>\d+))?)?`
+	version := `(?P<v>v)?` + core + `(?:(?P<extended>-(?P<branch>\w+)` + sep + `(?P<commit_distance>\d+)` + sep + `(?P<commit_hash>\w+))|-(?P<prerelease>[0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?(?:\+(?P<metadata>[0-9A-Za-z.-]+))?`
+	end := ""
+	if opts.Strict {
+		end = `$`
+	}
+	re := opts.Pattern
+	if re == nil {
+		var err error
+		// the prefix ends in - or /, like api- or api/, and is only taken when
+		// needed, so a prerelease like -rc-1 doesn't end up in it
+		expr := `(?P<prefix>.+?[-/])??` + version + end
+		if opts.Strict {
+			expr = `^` + expr
+		}
+		re, err = regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	semver := NewSemVer(0, 0, 0)
+	semver.Separator = separator
 	matches := re.FindStringSubmatch(input)
-
-	if matches[re.SubexpIndex("v")] == "v" {
-		semver.LeadingV = "v"
+	if matches == nil {
+		return nil, fmt.Errorf("no version found in '%s'", input)
+	}
+	isShort := func(matches []string) bool {
+		return matches[re.SubexpIndex("patch")] == "" && matches[re.SubexpIndex("v")] == ""
+	}
+	if opts.Pattern == nil && isShort(matches) {
+		// numbers in the prefix, like release-2024-1.2.3 or node-18-1.2.3,
+		// read as a short version, so take more of the tag as prefix
+		rest := regexp.MustCompile(`^(?P<prefix>)` + version + end)
+		for i := len(matches[re.SubexpIndex("prefix")]); i < len(input); i++ {
+			if input[i] != '-' && input[i] != '/' {
+				continue
+			}
+			if longer := rest.FindStringSubmatch(input[i+1:]); longer != nil && !isShort(longer) {
+				longer[0] = input[:i+1] + longer[0]
+				longer[re.SubexpIndex("prefix")] = input[:i+1]
+				matches = longer
+				break
+			}
+		}
+		if isShort(matches) {
+			return nil, fmt.Errorf("no version found in '%s', short versions need a leading v", input)
+		}
+	}
+	if opts.Strict && matches[0] != input {
+		return nil, fmt.Errorf("unexpected content besides the version in '%s'", input)
+	}
+	// a custom pattern may lack the optional groups
+	group := func(name string) string {
+		if i := re.SubexpIndex(name); i >= 0 {
+			return matches[i]
+		}
+		return ""
 	}
 
-	major, err := strconv.ParseUint(matches[re.SubexpIndex("major")], 10, 32)
+	// keep the prefix and leading v of the tag, bare tags stay bare; a prefix
+	// like api/ of Go submodules keeps its separator
+	semver.Prefix = strings.TrimSuffix(group("prefix"), "-")
+	semver.LeadingV = group("v")
+
+	major, err := strconv.ParseUint(group("major"), 10, 32)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing major; %v", err)
 	}
 	semver.Major = major
 
-	minor, err := strconv.ParseUint(matches[re.SubexpIndex("minor")], 10, 32)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing minor; %v", err)
+	if group("minor") != "" {
+		minor, err := strconv.ParseUint(group("minor"), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing minor; %v", err)
+		}
+		semver.Minor = minor
 	}
-	semver.Minor = minor
 
-	patch, err := strconv.ParseUint(matches[re.SubexpIndex("patch")], 10, 32)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing patch; %v", err)
+	if group("patch") != "" {
+		patch, err := strconv.ParseUint(group("patch"), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing patch; %v", err)
+		}
+		semver.Patch = patch
 	}
-	semver.Patch = patch
 
-	if matches[re.SubexpIndex("extended")] != "" {
-		branch := matches[re.SubexpIndex("branch")]
-		commitDistance, err := strconv.ParseUint(matches[re.SubexpIndex("commit_distance")], 10, 32)
+	if group("extended") != "" {
+		branch := group("branch")
+		commitDistance, err := strconv.ParseUint(group("commit_distance"), 10, 32)
 		if err != nil {
 			return nil, fmt.Errorf("error parsing commit distance; %v", err)
 		}
-		commitHash := matches[re.SubexpIndex("commit_hash")]
+		commitHash := group("commit_hash")
 
 		semver.Ext = &SemVerExtended{branch, commitDistance, commitHash}
 	}
+	semver.Prerelease = group("prerelease")
+	semver.Metadata = group("metadata")
+	if opts.PlusPrerelease && semver.Prerelease == "" && semver.Ext == nil {
+		semver.Prerelease, semver.Metadata = semver.Metadata, ""
+	}
 	return semver, nil
 }
 
 func (s *SemVer) GreaterThan(other *SemVer) bool {
 	return s.Major > other.Major ||
 		(s.Major == other.Major && s.Minor > other.Minor) ||
-		(s.Major == other.Major && s.Minor == other.Minor && s.Patch > other.Patch)
+		(s.Major == other.Major && s.Minor == other.Minor && s.Patch > other.Patch) ||
+		(s.Major == other.Major && s.Minor == other.Minor && s.Patch == other.Patch &&
+			comparePrerelease(s.Prerelease, other.Prerelease) > 0)
+}
+
+// comparePrerelease compares the precedence of prereleases, where no
+// prerelease is a release and ranks above any prerelease
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	identifiersA, identifiersB := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(identifiersA) && i < len(identifiersB); i++ {
+		if c := compareIdentifier(identifiersA[i], identifiersB[i]); c != 0 {
+			return c
+		}
+	}
+	// a larger set of identifiers ranks higher
+	return len(identifiersA) - len(identifiersB)
+}
+
+// compareIdentifier compares numeric identifiers numerically, which rank
+// below alphanumeric identifiers that are compared lexically
+func compareIdentifier(a, b string) int {
+	numberA, errA := strconv.ParseUint(a, 10, 64)
+	numberB, errB := strconv.ParseUint(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil && numberA != numberB:
+		if numberA > numberB {
+			return 1
+		}
+		return -1
+	case errA == nil && errB != nil:
+		return -1
+	case errA != nil && errB == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// BumpLevelFrom returns the increment from the previous version: major, minor,
+// patch, none or invalid when the version is lower than the previous
+func (s *SemVer) BumpLevelFrom(prev *SemVer) string {
+	switch {
+	case prev.GreaterThan(s):
+		return "invalid"
+	case s.Major > prev.Major:
+		return "major"
+	case s.Minor > prev.Minor:
+		return "minor"
+	case s.Patch > prev.Patch:
+		return "patch"
+	default:
+		return "none"
+	}
+}
+
+// IsPrerelease tells whether the version is a prerelease, like v1.2.3-rc.1
+func (s *SemVer) IsPrerelease() bool {
+	return s.Ext != nil || s.Prerelease != ""
 }
 
 func (s *SemVer) SameBranch(other *SemVer) bool {
@@ -103,36 +318,24 @@ func (s *SemVer) SameBranch(other *SemVer) bool {
 }
 
 func (s *SemVer) IncMajor() SemVer {
-	return SemVer{
-		Prefix:   s.Prefix,
-		LeadingV: s.LeadingV,
-		Major:    s.Major + 1,
-		Minor:    0,
-		Patch:    0,
-		Ext:      s.Ext,
-	}
+	next := *s
+	next.Major, next.Minor, next.Patch = s.Major+1, 0, 0
+	next.Prerelease, next.Metadata = "", ""
+	return next
 }
 
 func (s *SemVer) IncMinor() SemVer {
-	return SemVer{
-		Prefix:   s.Prefix,
-		LeadingV: s.LeadingV,
-		Major:    s.Major,
-		Minor:    s.Minor + 1,
-		Patch:    0,
-		Ext:      s.Ext,
-	}
+	next := *s
+	next.Minor, next.Patch = s.Minor+1, 0
+	next.Prerelease, next.Metadata = "", ""
+	return next
 }
 
 func (s *SemVer) IncPatch() SemVer {
-	return SemVer{
-		Prefix:   s.Prefix,
-		LeadingV: s.LeadingV,
-		Major:    s.Major,
-		Minor:    s.Minor,
-		Patch:    s.Patch + 1,
-		Ext:      s.Ext,
-	}
+	next := *s
+	next.Patch = s.Patch + 1
+	next.Prerelease, next.Metadata = "", ""
+	return next
 }
 
 func (s *SemVer) SetBranch(branch string) SemVer {
@@ -169,14 +372,86 @@ func (s *SemVer) SetCommitHash(commitHash string) SemVer {
 func (s *SemVer) PrintTag(release bool) string {
 	var version string
 	if release || s.Ext == nil {
-		version = fmt.Sprintf("%s%d.%d.%d", s.LeadingV, s.Major, s.Minor, s.Patch)
+		switch {
+		case s.ShortTag && s.Minor == 0 && s.Patch == 0:
+			version = s.LeadingV + s.number(s.Major)
+		case s.ShortTag && s.Patch == 0:
+			version = s.LeadingV + s.number(s.Major) + "." + s.number(s.Minor)
+		default:
+			version = s.LeadingV + s.number(s.Major) + "." + s.number(s.Minor) + "." + s.number(s.Patch)
+		}
 	} else {
-		branch := branchStripCharacters.ReplaceAllString(s.Ext.Branch, "")
-		version = fmt.Sprintf("%s%d.%d.%d-%s.%d.%s", s.LeadingV, s.Major, s.Minor, s.Patch, branch, s.Ext.CommitDistance, s.Ext.CommitHash)
+		version = fmt.Sprintf("%s%s.%s.%s", s.LeadingV, s.number(s.Major), s.number(s.Minor), s.number(s.Patch))
 	}
-	if s.Prefix != "" {
-		return strings.Join([]string{s.Prefix, version}, "-")
-	} else {
+	if label := s.PrereleaseLabel(release); label != "" {
+		version += "-" + label
+	}
+	if s.Metadata != "" {
+		version += "+" + s.Metadata
+	}
+	if s.PrefixOnRelease && !release && s.IsPrerelease() {
 		return version
 	}
+	return s.TagPrefix() + version
+}
+
+// TagPrefix returns the prefix with its separator, like api- or api/; the
+// prefix may be given with or without the separator, which defaults to -
+func (s *SemVer) TagPrefix() string {
+	switch {
+	case s.Prefix == "" || s.Prefix == "-":
+		return ""
+	case strings.HasSuffix(s.Prefix, "-") || strings.HasSuffix(s.Prefix, "/"):
+		return s.Prefix
+	default:
+		return s.Prefix + "-"
+	}
+}
+
+// SetPrefix replaces the prefix, keeping the separator the version was
+// parsed with when the prefix has none, so api/v1.2.3 stays api/
+func (s *SemVer) SetPrefix(prefix string) {
+	if prefix != "" && prefix == PrefixName(prefix) && prefix == PrefixName(s.Prefix) {
+		return
+	}
+	s.Prefix = prefix
+}
+
+// PrefixName returns the prefix without its separator, like api for api/
+func PrefixName(prefix string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(prefix, "-"), "/")
+}
+
+// PrereleaseLabel returns the part after the dash, either the prerelease or
+// the extended branch, commit distance and commit hash
+func (s *SemVer) PrereleaseLabel(release bool) string {
+	switch {
+	case release:
+		return ""
+	case s.Ext != nil:
+		separator := s.Separator
+		if separator == "" {
+			separator = DefaultSeparator
+		}
+		branch := branchIdentifier(s.Ext.Branch)
+		return fmt.Sprintf("%s%s%d%s%s", branch, separator, s.Ext.CommitDistance, separator, hashIdentifier(s.Ext.CommitHash))
+	default:
+		return s.Prerelease
+	}
+}
+
+// number formats a version number with the padding
+func (s *SemVer) number(n uint64) string {
+	return fmt.Sprintf("%0*d", s.Padding, n)
+}
+
+// Core returns the bare MAJOR.MINOR.PATCH, without prefix, leading v or
+// extended information
+func (s *SemVer) Core() string {
+	return fmt.Sprintf("%d.%d.%d", s.Major, s.Minor, s.Patch)
+}
+
+// String returns the version including extended information, see PrintTag
+func (s *SemVer) String() string {
+	return s.PrintTag(false)
 }
@@ -0,0 +1,383 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package semver
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseSemVerShortTags(t *testing.T) {
+	tests := []struct {
+		input               string
+		major, minor, patch uint64
+		wantErr             bool
+	}{
+		{input: "v1", major: 1},
+		{input: "v1.2", major: 1, minor: 2},
+		{input: "v1.2.3", major: 1, minor: 2, patch: 3},
+		{input: "1.2.3", major: 1, minor: 2, patch: 3},
+		{input: "api-v1.2", major: 1, minor: 2},
+		{input: "1.2", wantErr: true},
+		{input: "build-42", wantErr: true},
+		{input: "42", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got, err := ParseSemVer(test.input)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("ParseSemVer() = %s, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSemVer() error: %v", err)
+			}
+			if got.Major != test.major || got.Minor != test.minor || got.Patch != test.patch {
+				t.Errorf("ParseSemVer() = %d.%d.%d, want %d.%d.%d", got.Major, got.Minor, got.Patch, test.major, test.minor, test.patch)
+			}
+		})
+	}
+}
+
+func TestPrintTagShortTag(t *testing.T) {
+	tests := []struct {
+		major, minor, patch uint64
+		extended            bool
+		want                string
+	}{
+		{major: 1, minor: 2, patch: 3, want: "v1.2.3"},
+		{major: 1, minor: 2, want: "v1.2"},
+		{major: 1, want: "v1"},
+		{major: 1, minor: 0, patch: 1, want: "v1.0.1"},
+		{major: 1, extended: true, want: "v1.0.0-main.4.63ee8c4"},
+	}
+	for _, test := range tests {
+		t.Run(test.want, func(t *testing.T) {
+			version := NewSemVer(test.major, test.minor, test.patch)
+			version.LeadingV = "v"
+			version.ShortTag = true
+			if test.extended {
+				version.SetBranch("main")
+				version.SetCommitDistance(4)
+				version.SetCommitHash("63ee8c4")
+			}
+			got := version.PrintTag(false)
+			if got != test.want {
+				t.Fatalf("PrintTag() = %s, want %s", got, test.want)
+			}
+			parsed, err := ParseSemVer(got)
+			if err != nil {
+				t.Fatalf("ParseSemVer() error: %v", err)
+			}
+			if parsed.Major != test.major || parsed.Minor != test.minor || parsed.Patch != test.patch {
+				t.Errorf("ParseSemVer() = %d.%d.%d, want %d.%d.%d", parsed.Major, parsed.Minor, parsed.Patch, test.major, test.minor, test.patch)
+			}
+		})
+	}
+}
+
+func TestShortTagsInHistory(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("fix: a")
+	// floating tags sit on the latest release
+	r.tag("v1.2.3")
+	r.tag("v1.2")
+	r.tag("v1")
+	r.commit("fix: b")
+	r.tag("build-42")
+	r.commit("fix: c")
+
+	if got := r.version(options()); got != "v1.2.4" {
+		t.Errorf("SemVer() = %s, want v1.2.4", got)
+	}
+}
+
+func TestPrereleaseIdentifiers(t *testing.T) {
+	tests := []struct {
+		branch string
+		hash   string
+		want   string
+	}{
+		{branch: "feature/login", hash: "63ee8c4", want: "v1.2.3-featurelogin.4.63ee8c4"},
+		{branch: "feature/123", hash: "63ee8c4", want: "v1.2.3-feature123.4.63ee8c4"},
+		{branch: "fix-bug_2", hash: "63ee8c4", want: "v1.2.3-fix-bug2.4.63ee8c4"},
+		{branch: "00123", hash: "63ee8c4", want: "v1.2.3-123.4.63ee8c4"},
+		{branch: "000", hash: "63ee8c4", want: "v1.2.3-0.4.63ee8c4"},
+		{branch: "0abc", hash: "63ee8c4", want: "v1.2.3-0abc.4.63ee8c4"},
+		{branch: "ÿ/_", hash: "63ee8c4", want: "v1.2.3-branch.4.63ee8c4"},
+		{branch: "main", hash: "0123456", want: "v1.2.3-main.4.g0123456"},
+		{branch: "main", hash: "1234567", want: "v1.2.3-main.4.1234567"},
+		{branch: "main", hash: "0abcdef", want: "v1.2.3-main.4.0abcdef"},
+	}
+	for _, test := range tests {
+		t.Run(test.branch+" "+test.hash, func(t *testing.T) {
+			version := NewSemVer(1, 2, 3)
+			version.SetBranch(test.branch)
+			version.SetCommitDistance(4)
+			version.SetCommitHash(test.hash + "0000000000000000000000000000000000")
+			got := version.String()
+			if got != test.want {
+				t.Errorf("String() = %s, want %s", got, test.want)
+			}
+			if !IsStrict(got[1:]) {
+				t.Errorf("String() = %s isn't valid SemVer 2.0.0", got)
+			}
+		})
+	}
+}
+
+func TestBumpLevelFrom(t *testing.T) {
+	tests := []struct {
+		previous string
+		next     string
+		want     string
+	}{
+		{previous: "v1.2.3", next: "v2.0.0", want: "major"},
+		{previous: "v1.2.3", next: "v1.3.0", want: "minor"},
+		{previous: "v1.2.3", next: "v1.2.4", want: "patch"},
+		{previous: "v1.2.3", next: "v1.2.3", want: "none"},
+		{previous: "v1.2.3-rc.1", next: "v1.2.3", want: "none"},
+		{previous: "v1.2.3", next: "v1.2.2", want: "invalid"},
+		{previous: "v1.2.3", next: "v0.9.0", want: "invalid"},
+	}
+	for _, test := range tests {
+		t.Run(test.previous+" "+test.next, func(t *testing.T) {
+			previous, err := ParseSemVer(test.previous)
+			if err != nil {
+				t.Fatal(err)
+			}
+			next, err := ParseSemVer(test.next)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := next.BumpLevelFrom(previous); got != test.want {
+				t.Errorf("BumpLevelFrom() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSeparatorRoundTrip(t *testing.T) {
+	tests := []struct {
+		separator string
+		want      string
+	}{
+		{separator: ".", want: "v1.2.3-main.4.63ee8c4"},
+		{separator: "-", want: "v1.2.3-main-4-63ee8c4"},
+	}
+	for _, test := range tests {
+		t.Run(test.separator, func(t *testing.T) {
+			version := NewSemVer(1, 2, 3)
+			version.Separator = test.separator
+			version.SetBranch("main")
+			version.SetCommitDistance(4)
+			version.SetCommitHash("63ee8c40000000000000000000000000000000000")
+			printed := version.String()
+			if printed != test.want {
+				t.Fatalf("String() = %s, want %s", printed, test.want)
+			}
+
+			got, err := ParseSemVerWithOptions(printed, ParseOptions{Separator: test.separator})
+			if err != nil {
+				t.Fatalf("ParseSemVerWithOptions() error: %v", err)
+			}
+			if got.Ext == nil || got.Ext.Branch != "main" || got.Ext.CommitDistance != 4 || got.Ext.CommitHash != "63ee8c4" {
+				t.Fatalf("ParseSemVerWithOptions() extended = %+v, want main, 4 and 63ee8c4", got.Ext)
+			}
+			if got.String() != printed {
+				t.Errorf("String() after parsing = %s, want %s", got, printed)
+			}
+		})
+	}
+}
+
+func TestStringer(t *testing.T) {
+	version := NewSemVer(1, 2, 3)
+	version.LeadingV = "v"
+	version.SetBranch("main")
+	version.SetCommitDistance(4)
+	version.SetCommitHash("63ee8c4")
+	want := version.PrintTag(false)
+	for _, format := range []string{"%s", "%v"} {
+		if got := fmt.Sprintf(format, version); got != want {
+			t.Errorf("Sprintf(%q) = %s, want %s", format, got, want)
+		}
+	}
+}
+
+func TestBuildMetadataTag(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("feat: a")
+	r.tag("v1.2.3+ci.42")
+	if got := r.version(options()); got != "v1.2.3+ci.42" {
+		t.Errorf("SemVer() on the tag = %s, want v1.2.3+ci.42", got)
+	}
+	r.commit("fix: b")
+	if got := r.version(options()); got != "v1.2.4" {
+		t.Errorf("SemVer() = %s, want v1.2.4", got)
+	}
+}
+
+func TestCore(t *testing.T) {
+	tests := []string{"1.2.3", "v1.2.3", "api-v1.2.3", "api/v1.2.3-rc.1+ci.42", "v1.2.3-main.4.63ee8c4"}
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			version, err := ParseSemVer(input)
+			if err != nil {
+				t.Fatalf("ParseSemVer() error: %v", err)
+			}
+			if got := version.Core(); got != "1.2.3" {
+				t.Errorf("Core() = %s, want 1.2.3", got)
+			}
+		})
+	}
+}
+
+func TestCompilePattern(t *testing.T) {
+	tests := []struct {
+		expr    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{expr: `^release-\d{4}-(?P<major>\d+)\.(?P<minor>\d+)\.(?P<patch>\d+)$`, input: "release-2024-1.2.3", want: "1.2.3"},
+		{expr: `^build/(?P<major>\d+)_(?P<minor>\d+)_(?P<patch>\d+)$`, input: "build/4_5_6", want: "4.5.6"},
+		{expr: `^release-(?P<major>\d+)\.(?P<minor>\d+)$`, wantErr: true},
+		{expr: `^release-(?P<major>\d+`, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			pattern, err := CompilePattern(test.expr)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("CompilePattern() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CompilePattern() error: %v", err)
+			}
+			version, err := ParseSemVerWithOptions(test.input, ParseOptions{Pattern: pattern})
+			if err != nil {
+				t.Fatalf("ParseSemVerWithOptions() error: %v", err)
+			}
+			if got := version.Core(); got != test.want {
+				t.Errorf("ParseSemVerWithOptions() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPadding(t *testing.T) {
+	tests := []struct {
+		width int
+		want  string
+	}{
+		{width: 0, want: "v1.2.30"},
+		{width: 3, want: "v001.002.030"},
+		{width: 5, want: "v00001.00002.00030"},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.width), func(t *testing.T) {
+			version := NewSemVer(1, 2, 30)
+			version.LeadingV = "v"
+			version.Padding = test.width
+			if got := version.PrintTag(true); got != test.want {
+				t.Errorf("PrintTag() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseSemVerTrimmed(t *testing.T) {
+	tests := []string{"v1.2.3", " v1.2.3 ", "\tv1.2.3\n", `"v1.2.3"`, `'v1.2.3'`, ` "v1.2.3" `}
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			for _, strict := range []bool{false, true} {
+				got, err := ParseSemVerWithOptions(input, ParseOptions{Strict: strict})
+				if err != nil {
+					t.Fatalf("ParseSemVerWithOptions() strict %t error: %v", strict, err)
+				}
+				if got.String() != "v1.2.3" || got.Prefix != "" {
+					t.Errorf("ParseSemVerWithOptions() strict %t = %s with prefix %q, want v1.2.3", strict, got, got.Prefix)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSemVerTrailingContent(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr bool
+	}{
+		{input: "v1.2.3"},
+		{input: "v1.2.3-rc.1+ci.42"},
+		{input: "v1.2.3-main.4.63ee8c4"},
+		{input: "v1.2.3.4", wantErr: true},
+		{input: "v1.2.3-rc_1", wantErr: true},
+		{input: "v1.2.3+ci 42", wantErr: true},
+		{input: "v1.2.3-main.4.63ee8c4 dirty", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got, err := ParseSemVerWithOptions(test.input, ParseOptions{Strict: true})
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("ParseSemVerWithOptions() = %s, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSemVerWithOptions() error: %v", err)
+			}
+			if got.String() != test.input {
+				t.Errorf("ParseSemVerWithOptions() = %s, want %s", got, test.input)
+			}
+		})
+	}
+}
+
+func TestParseSemVerPlusPrerelease(t *testing.T) {
+	tests := []struct {
+		input          string
+		plusPrerelease bool
+		prerelease     string
+		metadata       string
+	}{
+		{input: "1.2.3+rc1", metadata: "rc1"},
+		{input: "1.2.3+rc1", plusPrerelease: true, prerelease: "rc1"},
+		{input: "1.2.3-rc.1+ci.42", plusPrerelease: true, prerelease: "rc.1", metadata: "ci.42"},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s %t", test.input, test.plusPrerelease), func(t *testing.T) {
+			got, err := ParseSemVerWithOptions(test.input, ParseOptions{PlusPrerelease: test.plusPrerelease})
+			if err != nil {
+				t.Fatalf("ParseSemVerWithOptions() error: %v", err)
+			}
+			if got.Prerelease != test.prerelease || got.Metadata != test.metadata {
+				t.Errorf("ParseSemVerWithOptions() = prerelease %q metadata %q, want %q and %q", got.Prerelease, got.Metadata, test.prerelease, test.metadata)
+			}
+		})
+	}
+
+	// as a prerelease, the legacy tag ranks below its release
+	rc, _ := ParseSemVerWithOptions("1.2.3+rc1", ParseOptions{PlusPrerelease: true})
+	release, _ := ParseSemVer("1.2.3")
+	if !release.GreaterThan(rc) || rc.GreaterThan(release) {
+		t.Errorf("1.2.3 doesn't rank above 1.2.3+rc1 as prerelease")
+	}
+}
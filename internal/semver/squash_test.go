@@ -0,0 +1,87 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package semver
+
+import "testing"
+
+func TestSquashWorkflow(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(r *testRepo)
+		want  func(r *testRepo) string
+	}{
+		{
+			name: "tagged squash commit",
+			build: func(r *testRepo) {
+				r.commit("fix: a")
+				r.tag("v1.0.0")
+				r.commit("feat: squashed feature (#1)")
+				r.tag("v1.1.0")
+			},
+			want: func(r *testRepo) string { return "v1.1.0" },
+		},
+		{
+			name: "branch cut before the squash",
+			build: func(r *testRepo) {
+				r.commit("fix: a")
+				r.tag("v1.0.0")
+				r.checkout("second")
+				r.commit("fix: second")
+				r.checkout("main")
+				r.commit("feat: squashed feature (#1)")
+				r.tag("v1.1.0")
+				r.checkout("second")
+			},
+			// the squash commit isn't an ancestor of the branch
+			want: func(r *testRepo) string { return "v1.0.1-second.1." + short(r.head()) },
+		},
+		{
+			name: "branch rebased onto the squash",
+			build: func(r *testRepo) {
+				r.commit("fix: a")
+				r.tag("v1.0.0")
+				r.commit("feat: squashed feature (#1)")
+				r.tag("v1.1.0")
+				r.checkout("second")
+				r.commit("fix: second")
+			},
+			want: func(r *testRepo) string { return "v1.1.1-second.1." + short(r.head()) },
+		},
+		{
+			name: "main merged into a branch cut before the squash",
+			build: func(r *testRepo) {
+				r.commit("fix: a")
+				r.tag("v1.0.0")
+				r.checkout("second")
+				r.commit("fix: second")
+				r.checkout("main")
+				r.commit("feat: squashed feature (#1)")
+				r.tag("v1.1.0")
+				r.checkout("second")
+				r.merge("main", "Merge branch 'main' into second")
+			},
+			// fix: second and the merge
+			want: func(r *testRepo) string { return "v1.1.1-second.2." + short(r.head()) },
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			test.build(r)
+			if got, want := r.version(options()), test.want(r); got != want {
+				t.Errorf("SemVer() = %s, want %s", got, want)
+			}
+		})
+	}
+}
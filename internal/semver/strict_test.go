@@ -0,0 +1,64 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package semver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStrictTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    []string
+		strict  bool
+		want    string
+		wantErr []string
+	}{
+		{name: "well-formed", tags: []string{"v1.2.3"}, strict: true, want: "v1.2.4"},
+		{name: "skipped", tags: []string{"v1.2.3", "release", "v1.2.3.4"}, want: "v1.2.4"},
+		{name: "malformed", tags: []string{"v1.2.3", "release", "v1.2.3.4"}, strict: true, wantErr: []string{"release", "v1.2.3.4"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("feat: a")
+			for _, tag := range test.tags {
+				r.tag(tag)
+			}
+			r.commit("fix: b")
+
+			opts := options()
+			opts.Strict = test.strict
+			got, err := NewConventionalCommits(r.repo, opts).SemVer()
+			if test.wantErr != nil {
+				if err == nil {
+					t.Fatalf("SemVer() = %s, want an error", got)
+				}
+				for _, tag := range test.wantErr {
+					if !strings.Contains(err.Error(), tag) {
+						t.Errorf("SemVer() error = %v, want it to list %s", err, tag)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SemVer() error: %v", err)
+			}
+			if got.String() != test.want {
+				t.Errorf("SemVer() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
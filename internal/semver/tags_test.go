@@ -0,0 +1,252 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package semver
+
+import (
+	"bytes"
+	"log"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestTagsOnOneCommit(t *testing.T) {
+	tests := []struct {
+		name string
+		tags []string
+		want string
+	}{
+		{name: "numeric precedence", tags: []string{"v1.2.0", "v1.10.0"}, want: "v1.10.0"},
+		{name: "reversed", tags: []string{"v1.10.0", "v1.2.0"}, want: "v1.10.0"},
+		{name: "release over its prerelease", tags: []string{"v1.3.0", "v1.3.0-rc.1"}, want: "v1.3.0"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("feat: a")
+			for _, tag := range test.tags {
+				r.tag(tag)
+			}
+			r.commit("chore: b")
+
+			cc := NewConventionalCommits(r.repo, options())
+			if _, err := cc.SemVer(); err != nil {
+				t.Fatalf("SemVer() error: %v", err)
+			}
+			if got := cc.Explain().Previous; got != test.want {
+				t.Errorf("SemVer() stopped at %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCorruptTagObject(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("feat: a")
+	r.tag("v1.0.0")
+	// a tag object that doesn't decode, which would otherwise be taken for
+	// a lightweight tag of the object itself
+	obj := r.repo.Storer.NewEncodedObject()
+	obj.SetType(plumbing.TagObject)
+	writer, _ := obj.Writer()
+	writer.Write([]byte("object " + r.head().String() + "\ntype bogus\n"))
+	writer.Close()
+	hash, err := r.repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.setRef(plumbing.NewHashReference(plumbing.NewTagReferenceName("v9.0.0"), hash))
+	r.commit("fix: b")
+
+	var buf bytes.Buffer
+	opts := options()
+	opts.Logger = log.New(&buf, "", 0)
+	if got := r.version(opts); got != "v1.0.1" {
+		t.Errorf("SemVer() = %s, want v1.0.1", got)
+	}
+	if want := "skipping tag v9.0.0"; !strings.Contains(buf.String(), want) {
+		t.Errorf("SemVer() logged %q, want %q", buf.String(), want)
+	}
+}
+
+func TestTagsNotInAncestors(t *testing.T) {
+	tests := []struct {
+		name   string
+		tag    string
+		prefix string
+		want   string
+	}{
+		{name: "prefix", tag: "api-v1.0.0", prefix: "api", want: "tags with prefix 'api' exist in the repository, but not in ancestors of HEAD"},
+		{name: "no prefix", tag: "v1.0.0", want: "tags exist in the repository, but not in ancestors of HEAD (is the clone shallow?)"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("chore: init")
+			r.checkout("other")
+			r.commit("feat: elsewhere")
+			r.tag(test.tag)
+			r.checkout("main")
+			r.commit("fix: a")
+
+			opts := options()
+			opts.Prefix = test.prefix
+			got, err := NewConventionalCommits(r.repo, opts).SemVer()
+			if err == nil {
+				t.Fatalf("SemVer() = %s, want an error", got)
+			}
+			if !strings.Contains(err.Error(), test.want) {
+				t.Errorf("SemVer() error = %v, want %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestIgnoreTags(t *testing.T) {
+	tests := []struct {
+		name   string
+		ignore []string
+		want   string
+	}{
+		{name: "bogus tag counts", want: "v9.0.1"},
+		{name: "bogus tag ignored", ignore: []string{"v9.0.0"}, want: "v1.1.0"},
+		{name: "all tags ignored", ignore: []string{"v9.0.0", "v1.0.0"}, want: "0.1.0"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("chore: init")
+			r.tag("v1.0.0")
+			r.commit("feat: a")
+			r.tag("v9.0.0")
+			r.commit("fix: b")
+
+			opts := options()
+			opts.IgnoreTags = test.ignore
+			if got := r.version(opts); got != test.want {
+				t.Errorf("SemVer() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTagFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		want   string
+	}{
+		{name: "all tags", want: "v3.0.1"},
+		{name: "production tags", filter: `-prod$`, want: "v1.1.1"},
+		{name: "dated tags", filter: `^v\d+\.\d+\.\d+-2024\d{4}$`, want: "v2.0.1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("chore: init")
+			r.tag("v1.1.0-prod")
+			r.commit("fix: a")
+			r.tag("v1.1.0-staging")
+			r.tag("v2.0.0-20240102")
+			r.commit("fix: b")
+			r.tag("v3.0.0")
+			r.commit("fix: c")
+
+			opts := options()
+			if test.filter != "" {
+				opts.TagFilter = regexp.MustCompile(test.filter)
+			}
+			if got := r.version(opts); got != test.want {
+				t.Errorf("SemVer() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestEqualVersionTieBreak(t *testing.T) {
+	tests := []struct {
+		name       string
+		mainNewer  bool
+		wantTagged string
+	}{
+		{name: "main tagged later", mainNewer: true, wantTagged: "v1.1.0"},
+		{name: "branch tagged later", wantTagged: "1.1.0"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("chore: init")
+			r.tag("v1.0.0")
+			// the same version tagged on both sides, the tag of the later
+			// commit wins
+			r.checkout("feature")
+			if test.mainNewer {
+				r.commit("fix: a")
+				r.tag("1.1.0")
+				r.checkout("main")
+				r.commit("fix: b")
+				r.tag("v1.1.0")
+			} else {
+				r.checkout("main")
+				r.commit("fix: b")
+				r.tag("v1.1.0")
+				r.checkout("feature")
+				r.commit("fix: a")
+				r.tag("1.1.0")
+				r.checkout("main")
+			}
+			r.merge("feature", "Merge branch 'feature'")
+
+			cc := NewConventionalCommits(r.repo, options())
+			if _, err := cc.SemVer(); err != nil {
+				t.Fatalf("SemVer() error: %v", err)
+			}
+			if got := cc.Explain().Previous; got != test.wantTagged {
+				t.Errorf("Previous = %s, want %s", got, test.wantTagged)
+			}
+		})
+	}
+}
+
+func TestDraftTags(t *testing.T) {
+	tests := []struct {
+		name   string
+		drafts string
+		want   string
+	}{
+		{name: "draft tag counts", want: "v1.3.1"},
+		{name: "draft tag ignored", drafts: `-draft$`, want: "v1.3.0"},
+		{name: "other drafts", drafts: `^v1\.3\.0-draft\.\d+$`, want: "v1.3.1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("chore: init")
+			r.tag("v1.2.0")
+			r.commit("feat: a")
+			r.tag("v1.3.0-draft")
+			r.commit("fix: b")
+
+			opts := options()
+			if test.drafts != "" {
+				opts.DraftTags = regexp.MustCompile(test.drafts)
+			}
+			if got := r.version(opts); got != test.want {
+				t.Errorf("SemVer() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,413 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package semver
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// TestVersionRelativeToHead checks HEAD behind the tip of main, which has
+// newer versions that mustn't be taken
+func TestVersionRelativeToHead(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(r *testRepo) string
+	}{
+		{
+			name: "on a tag",
+			build: func(r *testRepo) string {
+				r.commit("feat: a")
+				r.tag("v1.0.0")
+				head := r.head()
+				r.commit("feat: b")
+				r.tag("v1.1.0")
+				r.commit("feat: c")
+				r.detach(head)
+				return "v1.0.0"
+			},
+		},
+		{
+			name: "past a tag",
+			build: func(r *testRepo) string {
+				r.commit("feat: a")
+				r.tag("v1.0.0")
+				head := r.commit("fix: b")
+				r.checkout("feature")
+				r.checkout("main")
+				r.commit("feat: c")
+				r.tag("v1.1.0")
+				r.commit("feat: d")
+				r.checkout("feature")
+				return "v1.0.1-feature.1." + short(head)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			want := test.build(r)
+			if got := r.version(options()); got != want {
+				t.Errorf("SemVer() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestPullRequestBase(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseBump string
+		prBase   string
+		want     string
+	}{
+		{name: "ahead of the base", prBase: "main", want: "v1.1.0"},
+		{name: "base with a breaking change", baseBump: "feat!: e", prBase: "main", want: "v2.0.0"},
+		{name: "base with a breaking change without -pr-base", baseBump: "feat!: e", want: "v1.1.0"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("feat: a")
+			r.tag("v1.0.0")
+			r.checkout("feature")
+			r.commit("fix: b")
+			r.commit("feat: c")
+			head := r.commit("fix: d")
+			if test.baseBump != "" {
+				r.checkout("main")
+				r.commit(test.baseBump)
+				r.checkout("feature")
+			}
+
+			opts := options()
+			opts.PRBase = test.prBase
+			want := test.want + "-feature.3." + short(head)
+			if got := r.version(opts); got != want {
+				t.Errorf("SemVer() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestMaxCommits(t *testing.T) {
+	tests := []struct {
+		maxCommits int
+		wantErr    bool
+	}{
+		{maxCommits: 0},
+		{maxCommits: 3},
+		{maxCommits: 2, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.maxCommits), func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("feat: a")
+			r.tag("v1.0.0")
+			r.commit("fix: b")
+			r.commit("fix: c")
+			r.commit("fix: d")
+
+			opts := options()
+			opts.MaxCommits = test.maxCommits
+			got, err := NewConventionalCommits(r.repo, opts).SemVer()
+			if test.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "no tag found within 2 commits") {
+					t.Errorf("SemVer() = %v, %v, want the limit error", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SemVer() error: %v", err)
+			}
+			if got.String() != "v1.0.1" {
+				t.Errorf("SemVer() = %s, want v1.0.1", got)
+			}
+		})
+	}
+}
+
+func TestTraverseError(t *testing.T) {
+	r := newTestRepo(t)
+	r.commit("feat: a")
+	r.tag("v1.0.0")
+	// the history of HEAD is broken by a parent that is missing
+	missing := plumbing.NewHash("1234567890123456789012345678901234567890")
+	r.commitWith("fix: b", []plumbing.Hash{missing}, nil)
+
+	got, err := NewConventionalCommits(r.repo, options()).SemVer()
+	if err == nil {
+		t.Fatalf("SemVer() = %s, want an error", got)
+	}
+	if !errors.Is(err, plumbing.ErrObjectNotFound) {
+		t.Errorf("SemVer() error = %v, want %v", err, plumbing.ErrObjectNotFound)
+	}
+}
+
+func TestChannels(t *testing.T) {
+	tests := []struct {
+		branch  string
+		channel string
+	}{
+		{branch: "feature/login", channel: "alpha"},
+		{branch: "hotfix/crash", channel: "beta"},
+		{branch: "release/1.x", channel: "rc"},
+		{branch: "topic", channel: "topic"},
+	}
+	for _, test := range tests {
+		t.Run(test.branch, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("chore: init")
+			r.tag("v1.0.0")
+			r.checkout(test.branch)
+			head := r.commit("fix: a")
+
+			opts := options()
+			opts.Channels = DefaultChannels
+			want := "v1.0.1-" + test.channel + ".1." + short(head)
+			if got := r.version(opts); got != want {
+				t.Errorf("SemVer() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestHeadBranchFromGitHub(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{name: "pull request", env: map[string]string{"GITHUB_HEAD_REF": "feature/login", "GITHUB_REF_NAME": "12/merge", "GITHUB_REF_TYPE": "branch"}, want: "featurelogin"},
+		{name: "push to main", env: map[string]string{"GITHUB_REF_NAME": "main", "GITHUB_REF_TYPE": "branch"}},
+		{name: "push to a branch", env: map[string]string{"GITHUB_REF_NAME": "next", "GITHUB_REF_TYPE": "branch"}, want: "next"},
+		{name: "tag push", env: map[string]string{"GITHUB_REF_NAME": "v1.0.0", "GITHUB_REF_TYPE": "tag"}, want: "HEAD"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("chore: init")
+			r.tag("v1.0.0")
+			head := r.commit("fix: a")
+			// actions check out a detached HEAD
+			r.detach(head)
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+
+			want := "v1.0.1"
+			if test.want != "" {
+				want += "-" + test.want + ".1." + short(head)
+			}
+			if got := r.version(options()); got != want {
+				t.Errorf("SemVer() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestLinear(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(r *testRepo)
+	}{
+		{
+			name: "main",
+			build: func(r *testRepo) {
+				r.commit("feat: b")
+				r.commit("fix: c")
+			},
+		},
+		{
+			name: "tagged HEAD",
+			build: func(r *testRepo) {
+				r.commit("feat: b")
+				r.tag("v1.1.0")
+			},
+		},
+		{
+			name: "feature branch",
+			build: func(r *testRepo) {
+				r.commit("fix: b")
+				r.tag("v1.0.1")
+				r.checkout("feature")
+				r.commit("feat!: c")
+				r.commit("docs: d")
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("feat: a")
+			r.tag("v1.0.0")
+			test.build(r)
+
+			// a single walk of a linear history finds what both walks find
+			dual := NewConventionalCommits(r.repo, options())
+			want, err := dual.SemVer()
+			if err != nil {
+				t.Fatalf("SemVer() error: %v", err)
+			}
+			opts := options()
+			opts.Linear = true
+			linear := NewConventionalCommits(r.repo, opts)
+			got, err := linear.SemVer()
+			if err != nil {
+				t.Fatalf("SemVer() error: %v", err)
+			}
+			if got.String() != want.String() {
+				t.Errorf("SemVer() = %s with Linear, want %s", got, want)
+			}
+			if !reflect.DeepEqual(linear.Explain(), dual.Explain()) {
+				t.Errorf("Explain() = %+v with Linear, want %+v", linear.Explain(), dual.Explain())
+			}
+		})
+	}
+}
+
+// BenchmarkLinear compares the single walk of Linear with both walks on a
+// long linear history
+func BenchmarkLinear(b *testing.B) {
+	r := newTestRepo(b)
+	r.commit("chore: init")
+	r.tag("v1.0.0")
+	for i := 0; i < 2000; i++ {
+		r.commit(fmt.Sprintf("fix: change %d", i), "CHANGELOG.md")
+	}
+	for _, linear := range []bool{false, true} {
+		name := "dual walk"
+		if linear {
+			name = "linear"
+		}
+		b.Run(name, func(b *testing.B) {
+			opts := options()
+			opts.Linear = linear
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if got := r.version(opts); got != "v1.0.1" {
+					b.Fatalf("SemVer() = %s, want v1.0.1", got)
+				}
+			}
+		})
+	}
+}
+
+// TestTagsOnBranchOnly checks mirrors that tag a branch other than main, which
+// the main traversal only reaches through the merge. The breaking commit below
+// the tags was released already.
+func TestTagsOnBranchOnly(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(r *testRepo)
+		want  string
+	}{
+		{
+			name: "release branch merged into main",
+			build: func(r *testRepo) {
+				r.checkout("release")
+				r.commit("fix: b")
+				r.tag("v1.0.0")
+				r.checkout("main")
+				r.commit("feat: c")
+				r.merge("release", "Merge branch 'release'")
+			},
+			want: "v1.1.0",
+		},
+		{
+			name: "release branch merged into main twice",
+			build: func(r *testRepo) {
+				r.checkout("release")
+				r.commit("fix: b")
+				r.tag("v1.0.0")
+				r.checkout("main")
+				r.merge("release", "Merge branch 'release'")
+				r.checkout("release")
+				r.commit("fix: d")
+				r.tag("v1.0.1")
+				r.checkout("main")
+				r.commit("feat: e")
+				r.merge("release", "Merge branch 'release'")
+			},
+			want: "v1.1.0",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("feat!: init")
+			test.build(r)
+			if got := r.version(options()); got != test.want {
+				t.Errorf("SemVer() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBaseTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(r *testRepo)
+		want  Explanation
+	}{
+		{
+			name: "linear",
+			build: func(r *testRepo) {
+				r.tag("v1.0.0")
+				r.commit("fix: b")
+			},
+			want: Explanation{Previous: "v1.0.0", MainTag: "v1.0.0", BranchTag: "v1.0.0"},
+		},
+		{
+			name: "newer tag on the merged branch",
+			build: func(r *testRepo) {
+				r.tag("v1.0.0")
+				r.checkout("release")
+				r.commit("fix: b")
+				r.tag("v1.0.1")
+				r.checkout("main")
+				r.commit("fix: c")
+				r.merge("release", "Merge branch 'release'")
+			},
+			want: Explanation{Previous: "v1.0.1", MainTag: "v1.0.0", BranchTag: "v1.0.1"},
+		},
+		{
+			name: "untagged",
+			build: func(r *testRepo) {
+				r.commit("fix: b")
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.commit("feat: a")
+			test.build(r)
+			cc := NewConventionalCommits(r.repo, options())
+			if _, err := cc.SemVer(); err != nil {
+				t.Fatalf("SemVer() error: %v", err)
+			}
+			explain := cc.Explain()
+			got := Explanation{Previous: explain.Previous, MainTag: explain.MainTag, BranchTag: explain.BranchTag}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Explain() tags = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/koozz/gh-semver/internal/semver"
+)
+
+// lintCommand is the subcommand validating a commit message
+const lintCommand = "lint"
+
+// commitMessage returns the message of the lint arguments, read from the file
+// when given one, like the .git/COMMIT_EDITMSG of a commit-msg hook
+func commitMessage(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("usage: gh semver [flags] %s <message | file>", lintCommand)
+	}
+	message := strings.Join(args, " ")
+	if info, err := os.Stat(message); err != nil || info.IsDir() {
+		return message, nil
+	}
+	data, err := os.ReadFile(message)
+	if err != nil {
+		return "", fmt.Errorf("couldn't read commit message: %w", err)
+	}
+	// git drops the comment lines of the editor
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), nil
+}
+
+// lintMessage prints the bump level of the message, an unrecognized message
+// fails when strict
+func lintMessage(w io.Writer, cc *semver.ConventionalCommits, message string, strict bool) error {
+	level, conventional := cc.Lint(message)
+	if !conventional {
+		subject, _, _ := strings.Cut(message, "\n")
+		if strict {
+			return fmt.Errorf("not a conventional commit: '%s'", subject)
+		}
+		logger.Printf("warning: not a conventional commit: '%s'", subject)
+	}
+	fmt.Fprintln(w, level)
+	return nil
+}
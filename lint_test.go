@@ -0,0 +1,99 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/koozz/gh-semver/internal/semver"
+)
+
+func TestLintMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		opts    semver.Options
+		strict  bool
+		want    string
+		wantErr bool
+	}{
+		{message: "feat: add", want: "minor"},
+		{message: "fix(api): repair", want: "patch"},
+		{message: "feat!: drop", want: "major"},
+		{message: "fix: a\n\nBREAKING CHANGE: gone", want: "major"},
+		{message: "docs: explain", want: "none"},
+		{message: "perf: faster", opts: semver.Options{PatchTypes: []string{"perf"}}, want: "patch"},
+		{message: "fix:tight", want: "none"},
+		{message: "fix:tight", opts: semver.Options{Lenient: true}, want: "patch"},
+		{message: "Update README", want: "none"},
+		{message: "Update README", strict: true, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.message, func(t *testing.T) {
+			var out bytes.Buffer
+			err := lintMessage(&out, semver.NewConventionalCommits(nil, test.opts), test.message, test.strict)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("lintMessage() = %s, want an error", out.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("lintMessage() error: %v", err)
+			}
+			if got := strings.TrimSpace(out.String()); got != test.want {
+				t.Errorf("lintMessage() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCommitMessage(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "COMMIT_EDITMSG")
+	content := "feat: add\n\nbody\n# Please enter the commit message\n"
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{name: "message", args: []string{"fix: a"}, want: "fix: a"},
+		{name: "words", args: []string{"fix:", "a"}, want: "fix: a"},
+		{name: "file", args: []string{file}, want: "feat: add\n\nbody"},
+		{name: "none", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := commitMessage(test.args)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("commitMessage() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("commitMessage() error: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("commitMessage() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
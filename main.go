@@ -16,70 +16,690 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
+	"log"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/koozz/gh-semver/internal/semver"
 )
 
+const (
+	scanFull    = "full"
+	scanSubject = "subject"
+)
+
+// logger receives the informational messages and warnings, silenced by -quiet
+var logger = log.New(os.Stderr, "", 0)
+
+// planLogger receives what -dry-run would do, which is its only output and
+// isn't silenced by -quiet
+var planLogger = log.New(os.Stderr, "", 0)
+
 func main() {
 	var (
-		action     bool
-		filterPath string
-		prefix     string
-		release    bool
-		tag        bool
+		action          bool
+		allowDirty      bool
+		attest          string
+		baseTag         string
+		batch           bool
+		batchPatterns   stringList
+		breakingKeys    stringList
+		both            bool
+		buildNumber     bool
+		channelRules    stringList
+		channels        bool
+		cacheFile       string
+		changelog       bool
+		changelogFrom   string
+		changelogGroup  bool
+		commitlint      bool
+		compare         bool
+		configFile      string
+		coreOnly        bool
+		defaultBump     string
+		dotEnvFile      string
+		dotEnvParts     bool
+		draftTags       string
+		dryRun          bool
+		envOutput       bool
+		escalateMinors  int
+		escalatePatches int
+		filterPath      string
+		firstRelease    bool
+		fromGoMod       string
+		githubEnv       bool
+		ignoreTags      stringList
+		isolatePrefix   bool
+		jsonOutput      bool
+		labelRules      stringList
+		labels          bool
+		leadingV        string
+		lenient         bool
+		linear          bool
+		jsonl           bool
+		mainBranch      string
+		maxCommits      int
+		modules         stringList
+		modulesFile     string
+		noLeadingV      bool
+		noPrefixFilter  bool
+		notifyOnBump    bool
+		notifyURL       string
+		ociLabels       bool
+		outputName      string
+		padded          int
+		patchTypes      stringList
+		plusPrerelease  bool
+		prBase          string
+		prComment       bool
+		prerelease      string
+		printBase       bool
+		printMain       bool
+		push            bool
+		prefix          string
+		prefixOnRelease bool
+		quiet           bool
+		release         bool
+		remoteTags      string
+		rev             string
+		scan            string
+		scanBytes       int
+		separator       string
+		shortTag        bool
+		sign            bool
+		signingKey      string
+		stableDist      bool
+		stats           bool
+		strict          bool
+		tag             bool
+		tagBranches     stringList
+		tagFilter       string
+		tagPattern      string
+		tagType         string
+		updateFloating  bool
+		validate        string
+		validateOutput  bool
 	)
-	flag.BoolVar(&action, "action", false, "GitHub Action output format named 'version'")
+	flag.BoolVar(&action, "action", false, "GitHub Action outputs, the version is named by -output-name")
+	flag.BoolVar(&allowDirty, "allow-dirty-tag", false, "Commit the tag even though the worktree has uncommitted changes")
+	flag.StringVar(&attest, "attest", "", "Write a signed JSON attestation of the version to this file")
+	flag.StringVar(&baseTag, "base-tag", "", "The tag to increment from instead of the latest tag, like for a hotfix")
+	flag.Var(&breakingKeys, "breaking-keywords", "Footer keywords that bump the major, like INCOMPATIBLE (default BREAKING CHANGE)")
+	flag.BoolVar(&batch, "batch", false, "Take the bump of bors and mergify batch commits from the subjects in their body")
+	flag.Var(&batchPatterns, "batch-pattern", "A regular expression matching the subject of merge bot batch commits, like ^Merge #")
+	flag.BoolVar(&both, "both", false, "Output the release and prerelease version as JSON")
+	flag.BoolVar(&buildNumber, "build-number", false, "Output the number of commits reachable from HEAD, which increases with every commit")
+	flag.StringVar(&cacheFile, "cache-file", "", "Store the version in this file and reuse it on the next call while HEAD and the tags are unchanged")
+	flag.Var(&channelRules, "channel", "A pattern=channel rule replacing matching branches by a prerelease channel, like feature/*=alpha")
+	flag.BoolVar(&channels, "channels", false, "Use the alpha, beta and rc channels for feature/*, hotfix/* and release/* branches")
+	flag.BoolVar(&changelog, "changelog", false, "Output a Markdown changelog of the commits since the previous tag")
+	flag.StringVar(&changelogFrom, "changelog-from", "", "The older tag to start the changelog from (default the previous tag)")
+	flag.BoolVar(&changelogGroup, "changelog-grouped", false, "Group the changelog by the tags in between")
+	flag.BoolVar(&commitlint, "commitlint", false, "Read the allowed commit types from the commitlint config")
+	flag.BoolVar(&compare, "compare-url", false, "Output the URL comparing the previous and next tag")
+	flag.BoolVar(&coreOnly, "core-only", false, "Output only MAJOR.MINOR.PATCH, without prefix, leading v or extended information")
+	flag.StringVar(&configFile, "config", "", "The config file, relative to the repository root (default "+defaultConfigFile+")")
+	flag.StringVar(&defaultBump, "default-bump", "none", "The bump of commits since the tag when none calls for one, like patch for non-conventional messages")
+	flag.StringVar(&dotEnvFile, "dotenv", "", "Set VERSION in this .env file, like for docker compose")
+	flag.BoolVar(&dotEnvParts, "dotenv-components", false, "Also set MAJOR, MINOR, PATCH and PRERELEASE in the -dotenv file")
+	flag.StringVar(&draftTags, "draft-tags", "", "A regular expression matching placeholder tags that don't count as released, like -draft$")
+	flag.BoolVar(&dryRun, "dry-run", false, "Log the tag that would be committed and pushed without doing so")
+	flag.BoolVar(&envOutput, "env", false, "Output the version as shell variables, like eval $(gh semver -env)")
+	flag.IntVar(&escalateMinors, "escalate-minors", 0, "The number of minor changes that escalate to a major, 0 is never")
+	flag.IntVar(&escalatePatches, "escalate-patches", 0, "The number of patches that escalate to a minor, 0 is never")
 	flag.StringVar(&filterPath, "filter-path", "", "The path to filter commits (in case of a mono-repo)")
+	flag.BoolVar(&firstRelease, "first-release", false, "Graduate a 0.x version to 1.0.0, failing when already stable")
+	flag.StringVar(&fromGoMod, "from-gomod", "", "Derive prefix and filter path from the go.mod in this directory, relative to the repository root")
+	flag.BoolVar(&githubEnv, "github-env", false, "Append VERSION to the GITHUB_ENV file for the next steps of the job")
+	flag.Var(&ignoreTags, "ignore-tag", "Tags to calculate the version as if they don't exist, like one created by mistake")
+	flag.BoolVar(&isolatePrefix, "isolate-prefix", false, "Only consider tags with exactly the prefix, skipping those of other modules")
+	flag.BoolVar(&jsonOutput, "json", false, "Output the version as JSON")
+	flag.BoolVar(&jsonl, "jsonl", false, "Output the version of each -module as JSON Lines")
+	flag.Var(&labelRules, "label", "A label=bump rule forcing the bump of pull requests with the label, like breaking=major")
+	flag.BoolVar(&labels, "labels", false, "Force the bump of pull requests labeled semver:major, semver:minor, semver:patch or semver:none")
+	flag.StringVar(&leadingV, "leading-v", "v", "The leading v of the initial version")
+	flag.BoolVar(&lenient, "lenient", false, "Tolerate a missing or multiple spaces after the colon of a commit type")
+	flag.BoolVar(&linear, "linear", false, "Assume a history without merges to walk the commits once, which is faster")
+	flag.StringVar(&mainBranch, "main-branch", "", "The default branch of the repository (default asked from GitHub, or origin/HEAD)")
+	flag.IntVar(&maxCommits, "max-commits", 100000, "The maximum number of commits to walk to find a tag, 0 is unlimited")
+	flag.Var(&modules, "module", "A prefix:path module of a mono-repo to output the version of, the path defaults to prefix/")
+	flag.StringVar(&modulesFile, "modules-file", "", "A file relative to the repository root with a prefix: path module per line, like -module")
+	flag.BoolVar(&noLeadingV, "no-leading-v", false, "Output the version without leading v, regardless of -leading-v and the existing tags")
+	flag.BoolVar(&noPrefixFilter, "no-prefix-filter", false, "Consider all tags, while keeping the prefix on the output")
+	flag.BoolVar(&notifyOnBump, "notify-on-bump", false, "Only notify when the version is bumped")
+	flag.StringVar(&notifyURL, "notify-url", "", "POST the version as JSON to this URL")
+	flag.BoolVar(&ociLabels, "oci-labels", false, "Output OpenContainers image labels for docker build --label")
+	flag.StringVar(&outputName, "output-name", "version", "The name of the -action output holding the version")
+	flag.IntVar(&padded, "padded", 0, "Output the version with major, minor and patch zero-padded to this width")
+	flag.Var(&patchTypes, "patch-types", "Additional commit types that bump the patch, like perf or refactor")
+	flag.BoolVar(&plusPrerelease, "plus-prerelease", false, "Take the + segment of legacy tags like 1.2.3+rc1 as prerelease instead of build metadata")
+	flag.StringVar(&prBase, "pr-base", "", "The base branch of a pull request to compute the version it would produce")
+	flag.BoolVar(&prComment, "pr-comment", false, "Comment the version on the pull request of the branch")
+	flag.StringVar(&prerelease, "prerelease", "", "Output a numbered prerelease of this channel, like rc for v1.3.0-rc.1, continuing from the existing tags")
+	flag.BoolVar(&printBase, "base-tag-output", false, "Output the tag the version builds upon, and those found on the main and branch walk")
+	flag.BoolVar(&printMain, "print-main-branch", false, "Output the main branch and where it was resolved from")
+	flag.BoolVar(&push, "push", false, "Push the tag to "+defaultRemote)
 	flag.StringVar(&prefix, "prefix", "", "The prefix of the tag (in case of a mono-repo)")
+	flag.BoolVar(&quiet, "quiet", false, "Only print errors on stderr, no informational messages or warnings")
 	flag.BoolVar(&release, "release", false, "Force release tag")
+	flag.StringVar(&remoteTags, "remote-tags", "", "Also consider the tags on this remote, like origin")
+	flag.StringVar(&rev, "rev", "", "The revision to calculate the version for (default HEAD)")
+	flag.StringVar(&scan, "scan", scanFull, "The part of commit messages to scan, subject or full")
+	flag.IntVar(&scanBytes, "scan-bytes", 8192, "The number of bytes at the end of commit messages scanned for breaking change footers, 0 is unlimited")
+	flag.StringVar(&separator, "separator", semver.DefaultSeparator, "The separator between the branch, commit distance and commit hash")
+	flag.BoolVar(&shortTag, "short-tag", false, "Omit a zero patch (and minor) from release tags, like v1.2 or v1")
+	flag.BoolVar(&sign, "sign", false, "Sign the tag with the signing key")
+	flag.StringVar(&signingKey, "signing-key", "", "The armored private key file used for signing")
+	flag.BoolVar(&stableDist, "stable-distance", false, "Count the commit distance from the last stable tag, ignoring prerelease tags")
+	flag.BoolVar(&stats, "stats", false, "Output the number of breaking, feature, fix and other commits since the previous tag as JSON")
+	flag.BoolVar(&strict, "strict", false, "Fail on tags that aren't entirely a version, and lint on messages that aren't conventional commits")
 	flag.BoolVar(&tag, "tag", false, "Commit the tag")
+	flag.Var(&tagBranches, "tag-branches", "Branch patterns allowed to commit the tag (default the main branch)")
+	flag.BoolVar(&prefixOnRelease, "tag-prefix-only-on-release", false, "Omit the prefix from prerelease versions")
+	flag.StringVar(&tagFilter, "tag-filter", "", "A regular expression selecting the tags to calculate the version from")
+	flag.StringVar(&tagPattern, "tag-pattern", "", "A regular expression with the named groups major, minor and patch to parse non-standard tags")
+	flag.StringVar(&tagType, "tag-type", annotatedTag, "The type of tag to commit, annotated or lightweight")
+	flag.BoolVar(&updateFloating, "update-floating", false, "Move the floating major and minor tags, like v1 and v1.2, to the release")
+	flag.StringVar(&validate, "validate", "", "Fail unless this version is the version the commits call for")
+	flag.BoolVar(&validateOutput, "validate-output", false, "Fail unless the version, without prefix and leading v, is valid SemVer 2.0.0")
 	flag.Parse()
 
-	// open current repository
-	repo, err := git.PlainOpenWithOptions(".", &git.PlainOpenOptions{DetectDotGit: true})
+	// the subcommands only work on their arguments, so they don't open the
+	// repository and read the config of the working directory
+	subcommand := flag.Arg(0) == lintCommand || flag.Arg(0) == parseCommand
+	var repo *git.Repository
+	var worktree *git.Worktree
+	gitRoot := "."
+	var err error
+	if !subcommand {
+		// open current repository
+		repo, err = openRepository()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "couldn't open git repository: %v\n", err)
+			os.Exit(1)
+		}
+
+		worktree, gitRoot, err = repositoryRoot(repo)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	// a bare repository has no files to configure it
+	if gitRoot != "" {
+		if err := applyConfig(flag.CommandLine, gitRoot, configFile); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if quiet {
+		logger.SetOutput(io.Discard)
+	}
+
+	if modulesFile != "" && !subcommand {
+		values, err := loadModules(gitRoot, modulesFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		modules = append(modules, values...)
+	}
+
+	if fromGoMod != "" {
+		prefix, filterPath = goModPrefix(gitRoot, fromGoMod, prefix, filterPath)
+	}
+
+	var breakingTypes []string
+	if commitlint {
+		if breakingTypes, err = loadCommitlintTypes(gitRoot); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var customPattern *regexp.Regexp
+	if tagPattern != "" {
+		if customPattern, err = semver.CompilePattern(tagPattern); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if scan != scanFull && scan != scanSubject {
+		fmt.Fprintf(os.Stderr, "error: unknown scan '%s'\n", scan)
+		os.Exit(1)
+	}
+
+	defaultLevel, err := semver.ParseBumpLevel(defaultBump)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "couldn't open git repository: %v\n", err)
+		fmt.Fprintf(os.Stderr, "error: couldn't parse default bump: %v\n", err)
 		os.Exit(1)
 	}
 
-	tagVersion := calculateSemVer(repo, filterPath, prefix, action, release)
-	if tag {
-		gitTag(repo, tagVersion)
+	// the channel is followed by the counter, like rc.1
+	if prerelease != "" && !semver.IsStrict("0.0.0-"+prerelease+".1") {
+		fmt.Fprintf(os.Stderr, "error: invalid prerelease channel '%s'\n", prerelease)
+		os.Exit(1)
 	}
 
-	format := "%s\n"
-	if action {
-		format = "::set-output name=version::%s\n"
+	var tagFilterRegex *regexp.Regexp
+	if tagFilter != "" {
+		if tagFilterRegex, err = regexp.Compile(tagFilter); err != nil {
+			fmt.Fprintf(os.Stderr, "error: couldn't compile tag filter: %v\n", err)
+			os.Exit(1)
+		}
 	}
-	fmt.Printf(format, tagVersion)
-}
 
-func calculateSemVer(repo *git.Repository, filterPath, prefix string, action, release bool) string {
-	conventionalCommits := semver.NewConventionalCommits(repo, filterPath, prefix)
-	nextVersion, err := conventionalCommits.SemVer()
+	var batchRegexes []*regexp.Regexp
+	for _, pattern := range batchPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: couldn't compile batch pattern: %v\n", err)
+			os.Exit(1)
+		}
+		batchRegexes = append(batchRegexes, re)
+	}
+	if batch {
+		batchRegexes = append(batchRegexes, semver.DefaultBatchPatterns...)
+	}
+
+	var draftTagsRegex *regexp.Regexp
+	if draftTags != "" {
+		if draftTagsRegex, err = regexp.Compile(draftTags); err != nil {
+			fmt.Fprintf(os.Stderr, "error: couldn't compile draft tags: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	branchChannels, err := parseChannels(channelRules, channels)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v", err)
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
-	nextVersion.Prefix = prefix
 
-	return nextVersion.PrintTag(release)
-}
+	forceBump := semver.BumpUndecided
+	if len(labelRules) > 0 || labels {
+		bumpLabels, err := parseLabels(labelRules, labels)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		forceBump = labelBump(bumpLabels)
+	}
 
-func gitTag(repo *git.Repository, tagVersion string) {
-	if _, err := repo.Tag(tagVersion); err != nil {
-		headRef, err := repo.Head()
+	// the prefix is always put on the output, but only filters the tags when asked
+	filterPrefix := prefix
+	if noPrefixFilter {
+		filterPrefix = ""
+	}
+	opts := semver.Options{
+		FilterPath:       filterPath,
+		Prefix:           filterPrefix,
+		IsolatePrefix:    isolatePrefix,
+		Strict:           strict,
+		Separator:        separator,
+		TagPattern:       customPattern,
+		PlusPrerelease:   plusPrerelease,
+		ShortTag:         shortTag,
+		PRBase:           prBase,
+		PatchTypes:       patchTypes,
+		LeadingV:         leadingV,
+		MaxCommits:       maxCommits,
+		Lenient:          lenient,
+		RemoteTags:       remoteTags,
+		RemoteAuth:       tokenAuth(repo, remoteTags),
+		StableDistance:   stableDist,
+		BreakingTypes:    breakingTypes,
+		BreakingKeywords: breakingKeys,
+		Revision:         rev,
+		BaseTag:          baseTag,
+		IgnoreTags:       ignoreTags,
+		Channels:         branchChannels,
+		Prerelease:       prerelease,
+		DefaultBump:      defaultLevel,
+		ForceBump:        forceBump,
+		CacheFile:        cacheFile,
+		FirstRelease:     firstRelease,
+		Linear:           linear,
+		TagFilter:        tagFilterRegex,
+		DraftTags:        draftTagsRegex,
+		ScanSubject:      scan == scanSubject,
+		ScanBytes:        scanBytes,
+		BatchPatterns:    batchRegexes,
+		MainBranch:       mainBranch,
+		Escalation:       semver.EscalationPolicy{Patches: escalatePatches, Minors: escalateMinors},
+		Logger:           logger,
+	}
+	if flag.Arg(0) == lintCommand {
+		message, err := commitMessage(flag.Args()[1:])
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "error determining tag: %v\n", err)
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := lintMessage(os.Stdout, semver.NewConventionalCommits(repo, opts), message, strict); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if flag.Arg(0) == parseCommand {
+		parseOpts := semver.ParseOptions{Separator: separator, Pattern: customPattern, Strict: strict, PlusPrerelease: plusPrerelease}
+		if err := printParsed(os.Stdout, flag.Args()[1:], parseOpts); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(modules) > 0 {
+		mo := moduleOptions{opts, noPrefixFilter, prefixOnRelease, noLeadingV, release}
+		if err := printModules(os.Stdout, repo, modules, mo, jsonl); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	conventionalCommits := semver.NewConventionalCommits(repo, opts)
+	if printMain {
+		if err := printMainBranch(os.Stdout, conventionalCommits); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	nextVersion := calculateSemVer(conventionalCommits, prefix, prefixOnRelease, noLeadingV)
+	tagVersion := nextVersion.PrintTag(release)
+	if validateOutput {
+		if err := validateStrict(nextVersion, release); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if validate != "" {
+		parseOpts := semver.ParseOptions{Separator: separator, Pattern: customPattern, Strict: strict, PlusPrerelease: plusPrerelease}
+		if err := validateVersion(conventionalCommits.Explain(), nextVersion, validate, parseOpts); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if tag {
+		if err := checkTagBranch(conventionalCommits, tagBranches); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
 			os.Exit(1)
 		}
-		if _, err = repo.CreateTag(tagVersion, headRef.Hash(), &git.CreateTagOptions{
-			Message: tagVersion,
-		}); err != nil {
-			fmt.Fprintf(os.Stderr, "error creating tag: %v\v", err)
+		if !allowDirty && !isClean(worktree) {
+			fmt.Fprintf(os.Stderr, "error: the worktree has uncommitted changes, commit them or tag with -allow-dirty-tag\n")
 			os.Exit(1)
 		}
+		if tagType != annotatedTag && tagType != lightweightTag {
+			fmt.Fprintf(os.Stderr, "error: unknown tag type '%s'\n", tagType)
+			os.Exit(1)
+		}
+		var signer *openpgp.Entity
+		if sign {
+			if tagType != annotatedTag {
+				fmt.Fprintf(os.Stderr, "error: -sign requires an annotated tag\n")
+				os.Exit(1)
+			}
+			signer = requireSigningKey("-sign", signingKey)
+		}
+		target := plumbing.NewHash(conventionalCommits.Explain().Commit)
+		if rev != "" {
+			checkReachable(repo, target)
+		}
+		createdType := gitTag(repo, target, tagVersion, tagType, signer, dryRun)
+		if action {
+			if err := setOutput("tag-type", createdType); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if push {
+			pushTag(repo, tagVersion, target, false, dryRun)
+		}
+		if updateFloating && (release || !nextVersion.IsPrerelease()) {
+			for _, name := range floatingTags(nextVersion) {
+				moveFloatingTag(repo, target, name, dryRun)
+				if push {
+					pushTag(repo, name, target, true, dryRun)
+				}
+			}
+		}
+	}
+	if prComment {
+		if err := commentPullRequest(tagVersion, conventionalCommits.Explain(), dryRun); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if explain := conventionalCommits.Explain(); notifyURL != "" && (!notifyOnBump || explain.Bump != "none") {
+		if err := notify(notifyURL, newAttestation(explain, tagVersion)); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if githubEnv {
+		if err := writeGitHubEnv(os.Getenv("GITHUB_ENV"), tagVersion); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if dotEnvFile != "" {
+		entries := []dotEnv{{"VERSION", tagVersion}}
+		if dotEnvParts {
+			entries = append(entries,
+				dotEnv{"MAJOR", strconv.FormatUint(nextVersion.Major, 10)},
+				dotEnv{"MINOR", strconv.FormatUint(nextVersion.Minor, 10)},
+				dotEnv{"PATCH", strconv.FormatUint(nextVersion.Patch, 10)},
+				dotEnv{"PRERELEASE", nextVersion.PrereleaseLabel(release)},
+			)
+		}
+		if err := writeDotEnv(dotEnvFile, entries); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if attest != "" {
+		writeSignedAttestation(attest, signingKey, newAttestation(conventionalCommits.Explain(), tagVersion))
+	}
+
+	if compare {
+		url, err := compareURL(repo, conventionalCommits.Explain().Previous, tagVersion)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(url)
+		return
+	}
+	if changelog {
+		from := changelogFrom
+		if from == "" {
+			from = conventionalCommits.Explain().Previous
+		}
+		releases, err := conventionalCommits.Changelog(from, changelogGroup)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		printChangelog(os.Stdout, tagVersion, releases)
+		return
+	}
+	if ociLabels {
+		printOCILabels(os.Stdout, tagVersion, conventionalCommits.Explain())
+		return
+	}
+
+	if action {
+		outputs := actionOutputs(outputName, nextVersion, tagVersion, coreOnly, isClean(worktree), conventionalCommits.Explain().Commit)
+		for _, output := range outputs {
+			if err := setOutput(output[0], output[1]); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if summary := os.Getenv("GITHUB_STEP_SUMMARY"); summary != "" {
+			if err := writeStepSummary(summary, tagVersion, conventionalCommits.Explain()); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		return
+	}
+
+	if both {
+		output := bothOutput{Release: nextVersion.PrintTag(true), Prerelease: nextVersion.PrintTag(false)}
+		if err := printJSON(os.Stdout, output); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if printBase {
+		printBaseTag(os.Stdout, conventionalCommits.Explain())
+		return
+	}
+
+	if stats {
+		if err := printJSON(os.Stdout, conventionalCommits.Explain().Stats); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if buildNumber {
+		number, err := conventionalCommits.BuildNumber()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(number)
+		return
+	}
+
+	if coreOnly {
+		fmt.Println(nextVersion.Core())
+		return
+	}
+
+	if envOutput {
+		printEnv(os.Stdout, tagVersion, nextVersion, release)
+		return
+	}
+
+	if padded > 0 {
+		paddedVersion := *nextVersion
+		paddedVersion.Padding = padded
+		fmt.Println(paddedVersion.PrintTag(release))
+		return
+	}
+
+	if jsonOutput {
+		commit := conventionalCommits.Explain().Commit
+		output := versionOutput{Version: tagVersion, Clean: isClean(worktree), CommitHash: commit[:7], CommitHashFull: commit}
+		if err := printJSON(os.Stdout, output); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Println(tagVersion)
+}
+
+// parseChannels parses the pattern=channel rules, followed by the default
+// channels when asked
+func parseChannels(rules []string, defaults bool) ([]semver.Channel, error) {
+	var channels []semver.Channel
+	for _, rule := range rules {
+		pattern, channel, found := strings.Cut(rule, "=")
+		if !found || pattern == "" || channel == "" {
+			return nil, fmt.Errorf("channel rule '%s' isn't pattern=channel", rule)
+		}
+		channels = append(channels, semver.Channel{Pattern: pattern, Channel: channel})
+	}
+	if defaults {
+		channels = append(channels, semver.DefaultChannels...)
+	}
+	return channels, nil
+}
+
+func isClean(worktree *git.Worktree) bool {
+	// a bare repository has nothing uncommitted
+	if worktree == nil {
+		return true
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: couldn't get worktree status: %v\n", err)
+		os.Exit(1)
+	}
+	// like git describe --dirty, untracked files don't make it dirty, such as
+	// the cache or the .env file written by an earlier run
+	for _, file := range status {
+		if file.Staging != git.Untracked || file.Worktree != git.Untracked {
+			return false
+		}
+	}
+	return true
+}
+
+func calculateSemVer(conventionalCommits *semver.ConventionalCommits, prefix string, prefixOnRelease, noLeadingV bool) *semver.SemVer {
+	nextVersion, err := conventionalCommits.SemVer()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	nextVersion.SetPrefix(prefix)
+	nextVersion.PrefixOnRelease = prefixOnRelease
+	if noLeadingV {
+		nextVersion.LeadingV = ""
+	}
+
+	return nextVersion
+}
+
+func goModPrefix(gitRoot, dir, prefix, filterPath string) (string, string) {
+	modPrefix, modFilterPath, err := prefixFromGoMod(gitRoot, dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	// explicit flags take precedence
+	if prefix == "" {
+		prefix = modPrefix
+	}
+	if filterPath == "" {
+		filterPath = modFilterPath
+	}
+	return prefix, filterPath
+}
+
+func requireSigningKey(option, signingKey string) *openpgp.Entity {
+	if signingKey == "" {
+		fmt.Fprintf(os.Stderr, "error: %s requires -signing-key\n", option)
+		os.Exit(1)
+	}
+	signer, err := loadSigningKey(signingKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	return signer
+}
+
+func writeSignedAttestation(path, signingKey string, att attestation) {
+	signer := requireSigningKey("-attest", signingKey)
+	if err := writeAttestation(path, att, signer); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,312 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// mainArgsEnv holds the arguments for TestRunMain, one per line
+const mainArgsEnv = "GH_SEMVER_TEST_ARGS"
+
+// TestRunMain runs main with the arguments of runMain in a subprocess, as
+// main exits
+func TestRunMain(t *testing.T) {
+	args := os.Getenv(mainArgsEnv)
+	if args == "" {
+		t.Skip("only run by runMain")
+	}
+	os.Args = append([]string{"gh-semver"}, strings.Split(args, "\n")...)
+	main()
+	os.Exit(0)
+}
+
+// runMain runs gh-semver with the arguments in the directory and returns its
+// stdout and stderr
+func runMain(t *testing.T, dir string, args ...string) (string, string, error) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestRunMain$")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), mainArgsEnv+"="+strings.Join(args, "\n"),
+		"GITHUB_HEAD_REF=", "GITHUB_REF_NAME=", "GITHUB_REF_TYPE=", "GIT_DIR=")
+	var stdOut, stdErr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdOut, &stdErr
+	err := cmd.Run()
+	return stdOut.String(), stdErr.String(), err
+}
+
+// newRepositoryDir returns a directory with a repository, its commit of
+// README.md tagged v1.0.0 and followed by a fix
+func newRepositoryDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("couldn't init repository: %v", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, worktree.Filesystem, "README.md", "readme")
+	commitAll(t, worktree, "chore: init")
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateTag("v1.0.0", head.Hash(), nil); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, worktree.Filesystem, "CHANGELOG.md", "changes")
+	commitAll(t, worktree, "fix: a")
+	return dir
+}
+
+func TestIsClean(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, worktree *git.Worktree)
+		want   bool
+	}{
+		{
+			name:   "committed",
+			change: func(t *testing.T, worktree *git.Worktree) {},
+			want:   true,
+		},
+		{
+			name: "untracked file",
+			change: func(t *testing.T, worktree *git.Worktree) {
+				writeFile(t, worktree.Filesystem, ".semver-cache.json", "{}")
+			},
+			want: true,
+		},
+		{
+			name: "modified file",
+			change: func(t *testing.T, worktree *git.Worktree) {
+				writeFile(t, worktree.Filesystem, "README.md", "changed")
+			},
+			want: false,
+		},
+		{
+			name: "staged file",
+			change: func(t *testing.T, worktree *git.Worktree) {
+				writeFile(t, worktree.Filesystem, "main.go", "package main")
+				if _, err := worktree.Add("main.go"); err != nil {
+					t.Fatalf("couldn't add: %v", err)
+				}
+			},
+			want: false,
+		},
+		{
+			name: "deleted file",
+			change: func(t *testing.T, worktree *git.Worktree) {
+				if err := worktree.Filesystem.Remove("README.md"); err != nil {
+					t.Fatalf("couldn't remove: %v", err)
+				}
+			},
+			want: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, worktree := newWorktree(t)
+			test.change(t, worktree)
+			if got := isClean(worktree); got != test.want {
+				t.Errorf("isClean() = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestQuiet(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantStdErr string
+	}{
+		{name: "warning", wantStdErr: "warning: couldn't parse cache"},
+		{name: "quiet", args: []string{"-quiet"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := newRepositoryDir(t)
+			// an unreadable cache is warned about
+			if err := os.WriteFile(filepath.Join(dir, "cache.json"), []byte("{"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			args := append([]string{"-main-branch", "master", "-cache-file", "cache.json"}, test.args...)
+			stdOut, stdErr, err := runMain(t, dir, args...)
+			if err != nil {
+				t.Fatalf("gh-semver failed: %v\n%s", err, stdErr)
+			}
+			if stdOut != "v1.0.1\n" {
+				t.Errorf("gh-semver printed %q, want v1.0.1", stdOut)
+			}
+			if test.wantStdErr == "" && stdErr != "" || !strings.Contains(stdErr, test.wantStdErr) {
+				t.Errorf("gh-semver stderr = %q, want %q", stdErr, test.wantStdErr)
+			}
+		})
+	}
+}
+
+func TestBoth(t *testing.T) {
+	tests := []struct {
+		branch   string
+		extended bool
+	}{
+		{branch: "master"},
+		{branch: "feature", extended: true},
+	}
+	for _, test := range tests {
+		t.Run(test.branch, func(t *testing.T) {
+			dir := newRepositoryDir(t)
+			repo, err := git.PlainOpen(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.branch != "master" {
+				worktree, err := repo.Worktree()
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName(test.branch), Create: true})
+				if err != nil {
+					t.Fatalf("couldn't checkout %s: %v", test.branch, err)
+				}
+			}
+			stdOut, stdErr, err := runMain(t, dir, "-main-branch", "master", "-both")
+			if err != nil {
+				t.Fatalf("gh-semver failed: %v\n%s", err, stdErr)
+			}
+			var got bothOutput
+			if err := json.Unmarshal([]byte(stdOut), &got); err != nil {
+				t.Fatalf("couldn't decode output %q: %v", stdOut, err)
+			}
+			// both versions come from the one calculation
+			want := bothOutput{Release: "v1.0.1", Prerelease: "v1.0.1"}
+			if test.extended {
+				want.Prerelease += "-feature.1." + mustHead(t, repo).String()[:7]
+			}
+			if got != want {
+				t.Errorf("gh-semver -both = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestOutputName(t *testing.T) {
+	tests := []struct {
+		name       string
+		outputFile bool
+		want       string
+	}{
+		{name: "GITHUB_OUTPUT", outputFile: true, want: "semver=v1.0.1\n"},
+		{name: "set-output", want: "::set-output name=semver::v1.0.1\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := newRepositoryDir(t)
+			path := ""
+			if test.outputFile {
+				path = filepath.Join(t.TempDir(), "output")
+			}
+			t.Setenv("GITHUB_OUTPUT", path)
+			stdOut, stdErr, err := runMain(t, dir, "-main-branch", "master", "-action", "-output-name", "semver")
+			if err != nil {
+				t.Fatalf("gh-semver failed: %v\n%s", err, stdErr)
+			}
+			got := stdOut
+			if test.outputFile {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = string(data)
+			}
+			if !strings.HasPrefix(got, test.want) {
+				t.Errorf("gh-semver -output-name semver wrote %q, want %q first", got, test.want)
+			}
+			if strings.Contains(got, "version") {
+				t.Errorf("gh-semver -output-name semver wrote %q, want no version output", got)
+			}
+		})
+	}
+}
+
+func TestJSONCommitHash(t *testing.T) {
+	dir := newRepositoryDir(t)
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head := mustHead(t, repo).String()
+	stdOut, stdErr, err := runMain(t, dir, "-main-branch", "master", "-json")
+	if err != nil {
+		t.Fatalf("gh-semver failed: %v\n%s", err, stdErr)
+	}
+	var got versionOutput
+	if err := json.Unmarshal([]byte(stdOut), &got); err != nil {
+		t.Fatalf("couldn't decode output %q: %v", stdOut, err)
+	}
+	want := versionOutput{Version: "v1.0.1", Clean: true, CommitHash: head[:7], CommitHashFull: head}
+	if got != want {
+		t.Errorf("gh-semver -json = %+v, want %+v", got, want)
+	}
+}
+
+func TestBaseTagOutput(t *testing.T) {
+	dir := newRepositoryDir(t)
+	stdOut, stdErr, err := runMain(t, dir, "-main-branch", "master", "-base-tag-output")
+	if err != nil {
+		t.Fatalf("gh-semver failed: %v\n%s", err, stdErr)
+	}
+	if want := "v1.0.0 (main v1.0.0, branch v1.0.0)\n"; stdOut != want {
+		t.Errorf("gh-semver -base-tag-output printed %q, want %q", stdOut, want)
+	}
+}
+
+func TestQuietDryRun(t *testing.T) {
+	dir := newRepositoryDir(t)
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.CreateRemote(&config.RemoteConfig{Name: defaultRemote, URLs: []string{"https://example.invalid/repo.git"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdOut, stdErr, err := runMain(t, dir, "-main-branch", "master", "-tag", "-push", "-dry-run", "-quiet")
+	if err != nil {
+		t.Fatalf("gh-semver failed: %v\n%s", err, stdErr)
+	}
+	if stdOut != "v1.0.1\n" {
+		t.Errorf("gh-semver printed %q, want v1.0.1", stdOut)
+	}
+	// the plan is all a dry run has to say, -quiet or not
+	for _, want := range []string{"would create annotated tag v1.0.1", "would push refs/tags/v1.0.1:refs/tags/v1.0.1"} {
+		if !strings.Contains(stdErr, want) {
+			t.Errorf("gh-semver stderr = %q, want %q", stdErr, want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/koozz/gh-semver/internal/semver"
+)
+
+// monoModule is a versioned part of a mono-repo
+type monoModule struct {
+	Prefix  string `json:"prefix"`
+	Path    string `json:"-"`
+	Version string `json:"version"`
+}
+
+// parseModule parses a prefix:path module, the path defaults to the prefix
+// as directory
+func parseModule(value string) monoModule {
+	prefix, path, found := strings.Cut(value, ":")
+	if !found {
+		path = prefix + "/"
+	}
+	return monoModule{Prefix: prefix, Path: path}
+}
+
+// loadModules reads the modules of a mapping file, relative to the repository
+// root, with a prefix: path entry per line and # comments
+func loadModules(gitRoot, path string) ([]string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(gitRoot, path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read modules: %w", err)
+	}
+	defer file.Close()
+
+	var values []string
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		entry, _, _ := strings.Cut(scanner.Text(), "#")
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		prefix, modulePath, found := strings.Cut(entry, ":")
+		prefix, modulePath = strings.TrimSpace(prefix), strings.TrimSpace(modulePath)
+		if !found || prefix == "" || modulePath == "" {
+			return nil, fmt.Errorf("couldn't parse module on line %d of %s, expected prefix: path", line, path)
+		}
+		values = append(values, prefix+":"+modulePath)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("couldn't read modules: %w", err)
+	}
+	return values, nil
+}
+
+// moduleOptions are the options of a module, based on the options of the repository
+type moduleOptions struct {
+	opts            semver.Options
+	noPrefixFilter  bool
+	prefixOnRelease bool
+	noLeadingV      bool
+	release         bool
+}
+
+// printModules prints the version of each module, as JSON Lines when jsonl
+func printModules(w io.Writer, repo *git.Repository, values []string, mo moduleOptions, jsonl bool) error {
+	encoder := json.NewEncoder(w)
+	for _, value := range values {
+		m := parseModule(value)
+		opts := mo.opts
+		opts.FilterPath = m.Path
+		opts.Prefix = m.Prefix
+		if mo.noPrefixFilter {
+			opts.Prefix = ""
+		}
+		conventionalCommits := semver.NewConventionalCommits(repo, opts)
+		m.Version = calculateSemVer(conventionalCommits, m.Prefix, mo.prefixOnRelease, mo.noLeadingV).PrintTag(mo.release)
+		if !jsonl {
+			fmt.Fprintf(w, "%s %s\n", m.Prefix, m.Version)
+			continue
+		}
+		if err := encoder.Encode(m); err != nil {
+			return fmt.Errorf("couldn't encode module %s: %w", m.Prefix, err)
+		}
+	}
+	return nil
+}
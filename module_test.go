@@ -0,0 +1,163 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/koozz/gh-semver/internal/semver"
+)
+
+// twoModules returns a repository with the api and web modules, released
+// along with the repository itself, and a fix of api and a feature of web
+func twoModules(t *testing.T) *git.Repository {
+	t.Helper()
+	repo, worktree := newWorktree(t)
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tag := range []string{"v2.0.0", "api-v1.0.0", "web-v0.3.0"} {
+		if _, err := repo.CreateTag(tag, head.Hash(), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(t, worktree.Filesystem, "api/main.go", "package main")
+	commitAll(t, worktree, "fix: api")
+	writeFile(t, worktree.Filesystem, "web/index.html", "<html>")
+	commitAll(t, worktree, "feat: web")
+	return repo
+}
+
+func TestPrintModules(t *testing.T) {
+	tests := []struct {
+		name string
+		mo   moduleOptions
+		want string
+	}{
+		{
+			name: "prefix filter",
+			want: "api api-v1.0.1\nweb web-v0.4.0\n",
+		},
+		{
+			name: "no prefix filter",
+			mo:   moduleOptions{noPrefixFilter: true},
+			want: "api api-v2.0.1\nweb web-v2.1.0\n",
+		},
+		{
+			name: "no leading v",
+			mo:   moduleOptions{noLeadingV: true},
+			want: "api api-1.0.1\nweb web-0.4.0\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := twoModules(t)
+			mo := test.mo
+			mo.opts = semver.Options{MainBranch: "master"}
+			var buf bytes.Buffer
+			if err := printModules(&buf, repo, []string{"api", "web"}, mo, false); err != nil {
+				t.Fatalf("printModules() error: %v", err)
+			}
+			if got := buf.String(); got != test.want {
+				t.Errorf("printModules() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPrintModulesJSONL(t *testing.T) {
+	repo := twoModules(t)
+	var buf bytes.Buffer
+	mo := moduleOptions{opts: semver.Options{MainBranch: "master"}}
+	if err := printModules(&buf, repo, []string{"api", "web:web/"}, mo, true); err != nil {
+		t.Fatalf("printModules() error: %v", err)
+	}
+
+	want := []monoModule{{Prefix: "api", Version: "api-v1.0.1"}, {Prefix: "web", Version: "web-v0.4.0"}}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("printModules() = %q, want %d lines", buf.String(), len(want))
+	}
+	for i, line := range lines {
+		var got monoModule
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("line %d isn't JSON: %v", i+1, err)
+		}
+		if got != want[i] {
+			t.Errorf("line %d = %+v, want %+v", i+1, got, want[i])
+		}
+	}
+}
+
+func TestLoadModules(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "modules",
+			content: "# frontend and backend\napi: api\n\nweb : web/ # the site\ndocs: docs\n",
+			want:    []string{"api:api", "web:web/", "docs:docs"},
+		},
+		{name: "missing path", content: "api:\n", wantErr: true},
+		{name: "missing separator", content: "api\n", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			if err := os.WriteFile(filepath.Join(root, "MODULES"), []byte(test.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadModules(root, "MODULES")
+			if (err != nil) != test.wantErr {
+				t.Fatalf("loadModules() error = %v, want error %t", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("loadModules() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPrintModulesFromFile(t *testing.T) {
+	repo := twoModules(t)
+	path := filepath.Join(t.TempDir(), "MODULES")
+	if err := os.WriteFile(path, []byte("api: api\nweb: web/\ndocs: docs\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	values, err := loadModules("", path)
+	if err != nil {
+		t.Fatalf("loadModules() error: %v", err)
+	}
+	// the paths decide the commits of each module, from the repository tag
+	var buf bytes.Buffer
+	mo := moduleOptions{opts: semver.Options{MainBranch: "master"}, noPrefixFilter: true}
+	if err := printModules(&buf, repo, values, mo, false); err != nil {
+		t.Fatalf("printModules() error: %v", err)
+	}
+	want := "api api-v2.0.1\nweb web-v2.1.0\ndocs docs-v2.0.0\n"
+	if got := buf.String(); got != want {
+		t.Errorf("printModules() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	notifyAttempts = 3
+	notifyTimeout  = 10 * time.Second
+)
+
+// notifyBackoff is the wait before the second attempt, which grows with each
+// following attempt
+var notifyBackoff = time.Second
+
+// notify posts the version as JSON to the URL, retrying failed attempts
+func notify(url string, att attestation) error {
+	payload, err := json.Marshal(att)
+	if err != nil {
+		return fmt.Errorf("couldn't encode notification: %w", err)
+	}
+	client := &http.Client{Timeout: notifyTimeout}
+	for attempt := 1; ; attempt++ {
+		err = postJSON(client, url, payload)
+		if err == nil || attempt == notifyAttempts {
+			break
+		}
+		logger.Printf("warning: attempt %d to notify %s failed: %v", attempt, url, err)
+		time.Sleep(time.Duration(attempt) * notifyBackoff)
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't notify %s: %w", url, err)
+	}
+	return nil
+}
+
+func postJSON(client *http.Client, url string, payload []byte) error {
+	response, err := client.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", response.Status)
+	}
+	return nil
+}
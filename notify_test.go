@@ -0,0 +1,77 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotify(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		wantErr  bool
+	}{
+		{name: "posted"},
+		{name: "retried", failures: 2},
+		{name: "failed", failures: notifyAttempts, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			captureLog(t)
+			backoff := notifyBackoff
+			notifyBackoff = time.Millisecond
+			t.Cleanup(func() { notifyBackoff = backoff })
+
+			var attempts int
+			var got attestation
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts += 1
+				if attempts <= test.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("notify() sent %s with %s, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("notify() posted invalid JSON: %v", err)
+				}
+			}))
+			defer server.Close()
+
+			want := attestation{Commit: "63ee8c4d0f1a2b3c4d5e6f708192a3b4c5d6e7f8", Version: "v1.3.0", Previous: "v1.2.3", Bump: "minor"}
+			err := notify(server.URL, want)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("notify() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("notify() error: %v", err)
+			}
+			if got.Commit != want.Commit || got.Version != want.Version || got.Previous != want.Previous || got.Bump != want.Bump {
+				t.Errorf("notify() posted %+v, want %+v", got, want)
+			}
+			if attempts != test.failures+1 {
+				t.Errorf("notify() made %d attempts, want %d", attempts, test.failures+1)
+			}
+		})
+	}
+}
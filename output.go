@@ -0,0 +1,253 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/koozz/gh-semver/internal/semver"
+)
+
+// versionOutput is the JSON output of the calculated version
+type versionOutput struct {
+	Version        string `json:"version"`
+	Clean          bool   `json:"clean"`
+	CommitHash     string `json:"commitHash"`
+	CommitHashFull string `json:"commitHashFull"`
+}
+
+// bothOutput is the JSON output of the release and prerelease version of one
+// calculation
+type bothOutput struct {
+	Release    string `json:"release"`
+	Prerelease string `json:"prerelease"`
+}
+
+func printJSON(w io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// printOCILabels prints OpenContainers annotations usable with docker build --label
+func printOCILabels(w io.Writer, tagVersion string, explain *semver.Explanation) {
+	fmt.Fprintf(w, "org.opencontainers.image.version=%s\n", tagVersion)
+	fmt.Fprintf(w, "org.opencontainers.image.revision=%s\n", explain.Commit)
+}
+
+// writeStepSummary appends a Markdown summary of the version to the GitHub
+// Actions step summary file
+func writeStepSummary(path, tagVersion string, explain *semver.Explanation) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("couldn't open step summary: %w", err)
+	}
+	defer file.Close()
+
+	previous := explain.Previous
+	if previous == "" {
+		previous = "none"
+	}
+	fmt.Fprintf(file, "### Semantic version\n\n")
+	fmt.Fprintf(file, "| Previous | Next | Bump |\n| --- | --- | --- |\n")
+	fmt.Fprintf(file, "| %s | %s | %s |\n", previous, tagVersion, explain.Bump)
+	if len(explain.Reasons) > 0 {
+		fmt.Fprintf(file, "\nDeciding commits:\n\n")
+		for _, reason := range explain.Reasons {
+			fmt.Fprintf(file, "* `%.7s` %s (%s)\n", reason.Hash, reason.Subject, reason.Bump)
+		}
+	}
+	return nil
+}
+
+// actionOutputs are the step outputs of the version, which may be the bare
+// core while the tag is always the full name
+func actionOutputs(outputName string, version *semver.SemVer, tagVersion string, coreOnly, clean bool, commit string) [][2]string {
+	value := tagVersion
+	if coreOnly {
+		value = version.Core()
+	}
+	return [][2]string{
+		{outputName, value},
+		{"tag", tagVersion},
+		{"clean", strconv.FormatBool(clean)},
+		{"commit-hash", commit[:7]},
+		{"commit-hash-full", commit},
+	}
+}
+
+// setOutput sets a step output, in the GITHUB_OUTPUT file when the runner
+// provides one and with the legacy set-output command otherwise
+func setOutput(name, value string) error {
+	path := os.Getenv("GITHUB_OUTPUT")
+	if path == "" {
+		fmt.Printf("::set-output name=%s::%s\n", name, value)
+		return nil
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("couldn't open GITHUB_OUTPUT: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := fmt.Fprintf(file, "%s=%s\n", name, value); err != nil {
+		return fmt.Errorf("couldn't write GITHUB_OUTPUT: %w", err)
+	}
+	return nil
+}
+
+// writeGitHubEnv appends the version to the GITHUB_ENV file, which makes it
+// the VERSION environment variable of the next steps
+func writeGitHubEnv(path, tagVersion string) error {
+	if path == "" {
+		return fmt.Errorf("couldn't write the version: GITHUB_ENV isn't set")
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("couldn't open GITHUB_ENV: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := fmt.Fprintf(file, "VERSION=%s\n", tagVersion); err != nil {
+		return fmt.Errorf("couldn't write GITHUB_ENV: %w", err)
+	}
+	return nil
+}
+
+// changelogSections are the headings of the bump levels in a changelog
+var changelogSections = []struct{ bump, heading string }{
+	{"major", "Breaking changes"},
+	{"minor", "Features"},
+	{"patch", "Fixes"},
+}
+
+// printChangelog prints the releases as Markdown, the untagged release is the
+// next version
+func printChangelog(w io.Writer, tagVersion string, releases []semver.Release) {
+	for _, release := range releases {
+		if len(release.Changes) == 0 {
+			continue
+		}
+		tag := release.Tag
+		if tag == "" {
+			tag = tagVersion
+		}
+		fmt.Fprintf(w, "## %s\n", tag)
+		for _, section := range changelogSections {
+			var heading bool
+			for _, change := range release.Changes {
+				if change.Bump != section.bump {
+					continue
+				}
+				if !heading {
+					fmt.Fprintf(w, "\n### %s\n\n", section.heading)
+					heading = true
+				}
+				fmt.Fprintf(w, "* %s (`%.7s`)\n", change.Subject, change.Hash)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// printEnv prints the version as shell variable assignments for eval
+func printEnv(w io.Writer, tagVersion string, version *semver.SemVer, release bool) {
+	fmt.Fprintf(w, "VERSION=%s\n", shellQuote(tagVersion))
+	fmt.Fprintf(w, "MAJOR=%d\n", version.Major)
+	fmt.Fprintf(w, "MINOR=%d\n", version.Minor)
+	fmt.Fprintf(w, "PATCH=%d\n", version.Patch)
+	fmt.Fprintf(w, "PRERELEASE=%s\n", shellQuote(version.PrereleaseLabel(release)))
+}
+
+// dotEnv is a KEY=value entry of a .env file
+type dotEnv struct {
+	key, value string
+}
+
+// writeDotEnv sets the entries in the .env file, replacing the lines of keys
+// already in it and keeping the others
+func writeDotEnv(path string, entries []dotEnv) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("couldn't read %s: %w", path, err)
+	}
+	written := map[string]bool{}
+	var lines []string
+	if len(data) > 0 {
+		lines = strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	}
+	var kept []string
+	for _, line := range lines {
+		key, _, _ := strings.Cut(line, "=")
+		replaced := false
+		for _, entry := range entries {
+			if strings.TrimSpace(key) == entry.key {
+				// a key only occurs once, at its first line
+				if !written[entry.key] {
+					kept = append(kept, entry.key+"="+entry.value)
+					written[entry.key] = true
+				}
+				replaced = true
+			}
+		}
+		if !replaced {
+			kept = append(kept, line)
+		}
+	}
+	for _, entry := range entries {
+		if !written[entry.key] {
+			kept = append(kept, entry.key+"="+entry.value)
+		}
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(kept, "\n")+"\n"), 0o644); err != nil {
+		return fmt.Errorf("couldn't write %s: %w", path, err)
+	}
+	return nil
+}
+
+// shellQuote single-quotes a value, so the shell takes it literally
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// printBaseTag prints the tag the version builds upon, followed by the tags
+// the main and branch traversal found
+func printBaseTag(w io.Writer, explain *semver.Explanation) {
+	orNone := func(tag string) string {
+		if tag == "" {
+			return "none"
+		}
+		return tag
+	}
+	fmt.Fprintf(w, "%s (main %s, branch %s)\n", orNone(explain.Previous), orNone(explain.MainTag), orNone(explain.BranchTag))
+}
+
+// printMainBranch prints the main branch and where it was resolved from
+func printMainBranch(w io.Writer, conventionalCommits *semver.ConventionalCommits) error {
+	mainBranch, err := conventionalCommits.MainBranch()
+	if err != nil {
+		return err
+	}
+	source, err := conventionalCommits.MainBranchSource()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%s (%s)\n", mainBranch, source)
+	return nil
+}
@@ -0,0 +1,324 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/koozz/gh-semver/internal/semver"
+)
+
+func TestPrintOCILabels(t *testing.T) {
+	var buf bytes.Buffer
+	explain := &semver.Explanation{Commit: "63ee8c4d0f1a2b3c4d5e6f708192a3b4c5d6e7f8"}
+	printOCILabels(&buf, "v1.2.3", explain)
+	want := "org.opencontainers.image.version=v1.2.3\n" +
+		"org.opencontainers.image.revision=63ee8c4d0f1a2b3c4d5e6f708192a3b4c5d6e7f8\n"
+	if got := buf.String(); got != want {
+		t.Errorf("printOCILabels() = %q, want %q", got, want)
+	}
+}
+
+func TestPrintJSONClean(t *testing.T) {
+	tests := []struct {
+		name  string
+		dirty bool
+	}{
+		{name: "clean"},
+		{name: "dirty", dirty: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, worktree := newWorktree(t)
+			if test.dirty {
+				writeFile(t, worktree.Filesystem, "README.md", "changed")
+			}
+			var buf bytes.Buffer
+			output := versionOutput{Version: "v1.2.3", Clean: isClean(worktree)}
+			if err := printJSON(&buf, output); err != nil {
+				t.Fatalf("printJSON() error: %v", err)
+			}
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("couldn't decode output: %v", err)
+			}
+			if got["clean"] != !test.dirty {
+				t.Errorf("printJSON() clean = %v, want %t", got["clean"], !test.dirty)
+			}
+		})
+	}
+}
+
+func TestActionOutputs(t *testing.T) {
+	tests := []struct {
+		name     string
+		coreOnly bool
+		want     string
+	}{
+		{name: "tag", want: "version=api-v1.2.3\ntag=api-v1.2.3\n"},
+		{name: "core only", coreOnly: true, want: "version=1.2.3\ntag=api-v1.2.3\n"},
+	}
+	commitOutputs := "clean=true\ncommit-hash=63ee8c4\ncommit-hash-full=63ee8c4d0f1a2b3c4d5e6f708192a3b4c5d6e7f8\n"
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "output")
+			t.Setenv("GITHUB_OUTPUT", path)
+			version, err := semver.ParseSemVer("api-v1.2.3")
+			if err != nil {
+				t.Fatal(err)
+			}
+			commit := "63ee8c4d0f1a2b3c4d5e6f708192a3b4c5d6e7f8"
+			for _, output := range actionOutputs("version", version, version.PrintTag(true), test.coreOnly, true, commit) {
+				if err := setOutput(output[0], output[1]); err != nil {
+					t.Fatalf("setOutput() error: %v", err)
+				}
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := string(data), test.want+commitOutputs; got != want {
+				t.Errorf("outputs = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestWriteStepSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.md")
+	explain := &semver.Explanation{
+		Previous: "v1.2.3",
+		Bump:     "minor",
+		Reasons: []semver.BumpReason{
+			{Hash: "63ee8c4d0f1a2b3c4d5e6f708192a3b4c5d6e7f8", Subject: "feat: add", Bump: "minor"},
+		},
+	}
+	if err := writeStepSummary(path, "v1.3.0", explain); err != nil {
+		t.Fatalf("writeStepSummary() error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "### Semantic version\n\n" +
+		"| Previous | Next | Bump |\n| --- | --- | --- |\n" +
+		"| v1.2.3 | v1.3.0 | minor |\n" +
+		"\nDeciding commits:\n\n" +
+		"* `63ee8c4` feat: add (minor)\n"
+	if got := string(data); got != want {
+		t.Errorf("writeStepSummary() wrote %q, want %q", got, want)
+	}
+}
+
+func TestPrintChangelog(t *testing.T) {
+	releases := []semver.Release{
+		{Changes: []semver.BumpReason{{Hash: "1111111aaaa", Subject: "fix: d", Bump: "patch"}}},
+		{Tag: "v2.0.0", Changes: []semver.BumpReason{
+			{Hash: "2222222bbbb", Subject: "feat!: c", Bump: "major"},
+			{Hash: "3333333cccc", Subject: "docs: c", Bump: "none"},
+		}},
+		{Tag: "v1.1.1"},
+	}
+	var buf bytes.Buffer
+	printChangelog(&buf, "v2.0.1", releases)
+	want := "## v2.0.1\n\n### Fixes\n\n* fix: d (`1111111`)\n\n" +
+		"## v2.0.0\n\n### Breaking changes\n\n* feat!: c (`2222222`)\n\n"
+	if got := buf.String(); got != want {
+		t.Errorf("printChangelog() = %q, want %q", got, want)
+	}
+}
+
+func TestPrintEnv(t *testing.T) {
+	version := semver.NewSemVer(1, 2, 3)
+	version.LeadingV = "v"
+	version.SetBranch("feature/it's-$HOME")
+	version.SetCommitDistance(4)
+	version.SetCommitHash("63ee8c4")
+	var buf bytes.Buffer
+	printEnv(&buf, version.PrintTag(false), version, false)
+	want := "VERSION='v1.2.3-featureits-HOME.4.63ee8c4'\n" +
+		"MAJOR=1\nMINOR=2\nPATCH=3\n" +
+		"PRERELEASE='featureits-HOME.4.63ee8c4'\n"
+	if got := buf.String(); got != want {
+		t.Errorf("printEnv() = %q, want %q", got, want)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "v1.2.3", want: `'v1.2.3'`},
+		{value: "", want: `''`},
+		{value: "it's $HOME", want: `'it'\''s $HOME'`},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			if got := shellQuote(test.value); got != test.want {
+				t.Errorf("shellQuote() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+// stubDefaultBranch puts a gh on the path that reports the default branch of
+// the repository, or fails without one
+func stubDefaultBranch(t *testing.T, branch string) {
+	t.Helper()
+	dir := t.TempDir()
+	script := "#!/bin/sh\n[ -n \"$GH_DEFAULT_BRANCH\" ] || exit 1\nprintf '%s\\n' \"$GH_DEFAULT_BRANCH\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "gh"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("GH_PATH", "")
+	t.Setenv("GH_DEFAULT_BRANCH", branch)
+}
+
+func TestPrintMainBranch(t *testing.T) {
+	tests := []struct {
+		name       string
+		flag       string
+		gh         string
+		remoteHead string
+		want       string
+	}{
+		{name: "flag", flag: "trunk", gh: "main", want: "trunk (flag)\n"},
+		{name: "gh", gh: "main", remoteHead: "develop", want: "main (gh)\n"},
+		{name: "origin/HEAD", remoteHead: "develop", want: "develop (origin/HEAD)\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			captureLog(t)
+			stubDefaultBranch(t, test.gh)
+			repo, _ := newWorktree(t)
+			if test.remoteHead != "" {
+				remoteHead := plumbing.NewSymbolicReference(plumbing.NewRemoteHEADReferenceName("origin"), plumbing.NewRemoteReferenceName("origin", test.remoteHead))
+				if err := repo.Storer.SetReference(remoteHead); err != nil {
+					t.Fatal(err)
+				}
+			}
+			conventionalCommits := semver.NewConventionalCommits(repo, semver.Options{MainBranch: test.flag})
+			var buf bytes.Buffer
+			if err := printMainBranch(&buf, conventionalCommits); err != nil {
+				t.Fatalf("printMainBranch() error: %v", err)
+			}
+			if got := buf.String(); got != test.want {
+				t.Errorf("printMainBranch() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWriteGitHubEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "env")
+	// earlier steps wrote to the file as well
+	if err := os.WriteFile(path, []byte("NODE_ENV=test\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeGitHubEnv(path, "v1.2.3"); err != nil {
+		t.Fatalf("writeGitHubEnv() error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "NODE_ENV=test\nVERSION=v1.2.3\n"; got != want {
+		t.Errorf("writeGitHubEnv() wrote %q, want %q", got, want)
+	}
+	if err := writeGitHubEnv("", "v1.2.3"); err == nil {
+		t.Error("writeGitHubEnv() succeeded without GITHUB_ENV")
+	}
+}
+
+func TestPrintBaseTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		explain semver.Explanation
+		want    string
+	}{
+		{
+			name:    "both walks",
+			explain: semver.Explanation{Previous: "v1.0.1", MainTag: "v1.0.0", BranchTag: "v1.0.1"},
+			want:    "v1.0.1 (main v1.0.0, branch v1.0.1)\n",
+		},
+		{name: "initial version", want: "none (main none, branch none)\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			printBaseTag(&buf, &test.explain)
+			if got := buf.String(); got != test.want {
+				t.Errorf("printBaseTag() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWriteDotEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		entries  []dotEnv
+		want     string
+	}{
+		{
+			name:    "new file",
+			entries: []dotEnv{{"VERSION", "v1.2.3"}},
+			want:    "VERSION=v1.2.3\n",
+		},
+		{
+			name:     "other keys kept",
+			existing: "# compose\nIMAGE=app\n",
+			entries:  []dotEnv{{"VERSION", "v1.2.3"}, {"MAJOR", "1"}},
+			want:     "# compose\nIMAGE=app\nVERSION=v1.2.3\nMAJOR=1\n",
+		},
+		{
+			name:     "existing keys replaced once",
+			existing: "VERSION=v1.2.2\nIMAGE=app\nVERSION=v1.0.0\nMAJOR = 0\n",
+			entries:  []dotEnv{{"VERSION", "v1.2.3"}, {"MAJOR", "1"}},
+			want:     "VERSION=v1.2.3\nIMAGE=app\nMAJOR=1\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".env")
+			if test.existing != "" {
+				if err := os.WriteFile(path, []byte(test.existing), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := writeDotEnv(path, test.entries); err != nil {
+				t.Fatalf("writeDotEnv() error: %v", err)
+			}
+			// writing again changes nothing
+			if err := writeDotEnv(path, test.entries); err != nil {
+				t.Fatalf("writeDotEnv() error: %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(data); got != test.want {
+				t.Errorf("writeDotEnv() wrote %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/koozz/gh-semver/internal/semver"
+)
+
+// parseCommand is the subcommand printing how a tag is parsed
+const parseCommand = "parse"
+
+// parseOutput is the JSON output of a parsed tag
+type parseOutput struct {
+	Tag        string          `json:"tag"`
+	Prefix     string          `json:"prefix"`
+	LeadingV   string          `json:"v"`
+	Core       string          `json:"core"`
+	Prerelease string          `json:"prerelease,omitempty"`
+	Metadata   string          `json:"metadata,omitempty"`
+	Extended   *extendedOutput `json:"extended,omitempty"`
+}
+
+// extendedOutput is the extended information of a parsed tag
+type extendedOutput struct {
+	Branch         string `json:"branch"`
+	CommitDistance uint64 `json:"commitDistance"`
+	CommitHash     string `json:"commitHash"`
+}
+
+// printParsed prints the parse result of the tag as JSON
+func printParsed(w io.Writer, args []string, opts semver.ParseOptions) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: gh semver [flags] %s <tag>", parseCommand)
+	}
+	version, err := semver.ParseSemVerWithOptions(args[0], opts)
+	if err != nil {
+		return fmt.Errorf("couldn't parse tag '%s': %w", args[0], err)
+	}
+	output := parseOutput{
+		Tag:        args[0],
+		Prefix:     version.Prefix,
+		LeadingV:   version.LeadingV,
+		Core:       version.Core(),
+		Prerelease: version.Prerelease,
+		Metadata:   version.Metadata,
+	}
+	if version.Ext != nil {
+		output.Extended = &extendedOutput{version.Ext.Branch, version.Ext.CommitDistance, version.Ext.CommitHash}
+	}
+	return printJSON(w, output)
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/koozz/gh-semver/internal/semver"
+)
+
+func TestPrintParsed(t *testing.T) {
+	tests := []struct {
+		tag     string
+		opts    semver.ParseOptions
+		want    parseOutput
+		wantErr bool
+	}{
+		{tag: "v1.2.3", want: parseOutput{Tag: "v1.2.3", LeadingV: "v", Core: "1.2.3"}},
+		{tag: "api/v1.2.3-rc.1+build.5", want: parseOutput{Tag: "api/v1.2.3-rc.1+build.5", Prefix: "api/", LeadingV: "v", Core: "1.2.3", Prerelease: "rc.1", Metadata: "build.5"}},
+		{tag: "api-2.0.0", want: parseOutput{Tag: "api-2.0.0", Prefix: "api", Core: "2.0.0"}},
+		{
+			tag:  "v1.3.0-feature.4.63ee8c4",
+			want: parseOutput{Tag: "v1.3.0-feature.4.63ee8c4", LeadingV: "v", Core: "1.3.0", Extended: &extendedOutput{"feature", 4, "63ee8c4"}},
+		},
+		{
+			tag:  "v1.3.0-feature-4-63ee8c4",
+			opts: semver.ParseOptions{Separator: "-"},
+			want: parseOutput{Tag: "v1.3.0-feature-4-63ee8c4", LeadingV: "v", Core: "1.3.0", Extended: &extendedOutput{"feature", 4, "63ee8c4"}},
+		},
+		{tag: "release v1.2.3", opts: semver.ParseOptions{Strict: true}, wantErr: true},
+		{tag: "latest", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.tag, func(t *testing.T) {
+			var out bytes.Buffer
+			err := printParsed(&out, []string{test.tag}, test.opts)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("printParsed() = %s, want an error", out.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("printParsed() error: %v", err)
+			}
+			var got parseOutput
+			if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+				t.Fatalf("couldn't decode output: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("printParsed() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPrintParsedUsage(t *testing.T) {
+	for _, args := range [][]string{nil, {"v1.0.0", "v2.0.0"}} {
+		if err := printParsed(&bytes.Buffer{}, args, semver.ParseOptions{}); err == nil {
+			t.Errorf("printParsed(%v) succeeded, want a usage error", args)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/cli/go-gh"
+	"github.com/koozz/gh-semver/internal/semver"
+)
+
+// defaultLabels map the semver:* labels of a pull request to their bump
+var defaultLabels = map[string]semver.BumpLevel{
+	"semver:major": semver.BumpMajor,
+	"semver:minor": semver.BumpMinor,
+	"semver:patch": semver.BumpPatch,
+	"semver:none":  semver.BumpNone,
+}
+
+// pullRequestRef is the ref GitHub Actions checks out for a pull request
+var pullRequestRef = regexp.MustCompile(`^refs/pull/(\d+)/`)
+
+// pullRequestArgs returns the gh arguments of the pull request command, with
+// the number of the pull request GitHub Actions checked out
+func pullRequestArgs(command string) []string {
+	args := []string{"pr", command}
+	// outside of GitHub Actions gh finds the pull request of the branch
+	if match := pullRequestRef.FindStringSubmatch(os.Getenv("GITHUB_REF")); match != nil {
+		args = append(args, match[1])
+	}
+	return args
+}
+
+// parseLabels parses the label=bump rules, followed by the semver:* labels
+// when asked
+func parseLabels(rules []string, defaults bool) (map[string]semver.BumpLevel, error) {
+	labels := map[string]semver.BumpLevel{}
+	if defaults {
+		for label, level := range defaultLabels {
+			labels[label] = level
+		}
+	}
+	for _, rule := range rules {
+		label, name, found := strings.Cut(rule, "=")
+		if !found || label == "" {
+			return nil, fmt.Errorf("label rule '%s' isn't label=bump", rule)
+		}
+		level, err := semver.ParseBumpLevel(name)
+		if err != nil {
+			return nil, fmt.Errorf("label rule '%s': %w", rule, err)
+		}
+		labels[label] = level
+	}
+	return labels, nil
+}
+
+// labelBump returns the highest bump of the labels on the pull request,
+// undecided without pull request or matching label
+func labelBump(labels map[string]semver.BumpLevel) semver.BumpLevel {
+	stdOut, stdErr, err := gh.Exec(append(pullRequestArgs("view"), "--json", "labels", "--jq", ".labels[].name")...)
+	if err != nil {
+		logger.Printf("info: no pull request labels, the commits decide the bump: %s", strings.TrimSpace(stdErr.String()))
+		return semver.BumpUndecided
+	}
+	bump := semver.BumpUndecided
+	for _, name := range strings.Split(strings.TrimSpace(stdOut.String()), "\n") {
+		if level, found := labels[name]; found && level > bump {
+			bump = level
+		}
+	}
+	if bump != semver.BumpUndecided {
+		logger.Printf("info: pull request labels force a %s bump", bump)
+	}
+	return bump
+}
+
+// commentMarker marks the comments of the extension, to find them again
+const commentMarker = "<!-- gh-semver -->"
+
+// issueCommentURL is the URL of a pull request comment, ending in its id
+var issueCommentURL = regexp.MustCompile(`#issuecomment-(\d+)$`)
+
+// commentPullRequest posts the version the pull request would produce as a
+// comment, updating the earlier comment of the extension when there is one
+func commentPullRequest(tagVersion string, explain *semver.Explanation, dryRun bool) error {
+	body := pullRequestComment(tagVersion, explain)
+	if dryRun {
+		logger.Printf("info: would comment on the pull request:\n%s", body)
+		return nil
+	}
+	if id := markedComment(); id != "" {
+		_, stdErr, err := gh.Exec("api", "--method", "PATCH", "repos/{owner}/{repo}/issues/comments/"+id, "--field", "body="+body)
+		if err != nil {
+			return fmt.Errorf("couldn't update the pull request comment: %w: %s", err, strings.TrimSpace(stdErr.String()))
+		}
+		return nil
+	}
+	if _, stdErr, err := gh.Exec(append(pullRequestArgs("comment"), "--body", body)...); err != nil {
+		return fmt.Errorf("couldn't comment on the pull request: %w: %s", err, strings.TrimSpace(stdErr.String()))
+	}
+	return nil
+}
+
+// markedComment returns the id of the last comment with the marker on the pull
+// request, empty when there is none
+func markedComment() string {
+	jq := fmt.Sprintf(".comments[] | select(.body | contains(%q)) | .url", commentMarker)
+	stdOut, _, err := gh.Exec(append(pullRequestArgs("view"), "--json", "comments", "--jq", jq)...)
+	if err != nil {
+		return ""
+	}
+	urls := strings.Fields(stdOut.String())
+	if len(urls) == 0 {
+		return ""
+	}
+	if match := issueCommentURL.FindStringSubmatch(urls[len(urls)-1]); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// pullRequestComment returns the Markdown comment on the version
+func pullRequestComment(tagVersion string, explain *semver.Explanation) string {
+	var body strings.Builder
+	fmt.Fprintln(&body, commentMarker)
+	switch {
+	case explain.Previous == "":
+		fmt.Fprintf(&body, "This pull request would produce the initial version **%s**.\n", tagVersion)
+	default:
+		fmt.Fprintf(&body, "This pull request would produce **%s**, a %s bump from %s.\n", tagVersion, explain.Bump, explain.Previous)
+	}
+	if len(explain.Reasons) > 0 {
+		fmt.Fprintf(&body, "\nDeciding commits:\n\n")
+		for _, reason := range explain.Reasons {
+			fmt.Fprintf(&body, "* `%.7s` %s (%s)\n", reason.Hash, reason.Subject, reason.Bump)
+		}
+	}
+	return body.String()
+}
@@ -0,0 +1,169 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/koozz/gh-semver/internal/semver"
+)
+
+// stubGh puts a gh on the PATH that logs its arguments, one call per line,
+// and prints the output of pr view, failing it on fail
+func stubGh(t *testing.T, view string) string {
+	t.Helper()
+	dir := t.TempDir()
+	log := filepath.Join(dir, "gh.log")
+	script := `#!/bin/sh
+printf '%s\0' "$*" >> "$GH_LOG"
+if [ "$1 $2" = "pr view" ]; then
+	[ "$GH_VIEW" != fail ] || exit 1
+	printf '%s\n' "$GH_VIEW"
+fi
+`
+	if err := os.WriteFile(filepath.Join(dir, "gh"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("GH_LOG", log)
+	t.Setenv("GH_VIEW", view)
+	t.Setenv("GITHUB_REF", "")
+	return log
+}
+
+// ghCalls returns the arguments of the calls to the stubbed gh
+func ghCalls(t *testing.T, log string) []string {
+	t.Helper()
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatalf("couldn't read gh log: %v", err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\x00"), "\x00")
+}
+
+func TestCommentPullRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		view string
+		want string
+	}{
+		{name: "no pull request", view: "fail", want: "pr comment --body " + commentMarker},
+		{name: "no comment of the extension", view: "", want: "pr comment --body " + commentMarker},
+		{
+			name: "comment of the extension",
+			view: "https://github.com/o/r/pull/1#issuecomment-11\nhttps://github.com/o/r/pull/1#issuecomment-42",
+			want: "api --method PATCH repos/{owner}/{repo}/issues/comments/42 --field body=" + commentMarker,
+		},
+	}
+	explain := &semver.Explanation{Previous: "v1.2.3", Bump: "minor"}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			log := stubGh(t, test.view)
+			if err := commentPullRequest("v1.3.0", explain, false); err != nil {
+				t.Fatalf("commentPullRequest() error: %v", err)
+			}
+			calls := ghCalls(t, log)
+			if len(calls) != 2 || !strings.HasPrefix(calls[1], test.want) {
+				t.Errorf("commentPullRequest() called gh %q, want a second call %q", calls, test.want)
+			}
+		})
+	}
+}
+
+func TestCommentPullRequestDryRun(t *testing.T) {
+	log := stubGh(t, "fail")
+	if err := commentPullRequest("v1.3.0", &semver.Explanation{}, true); err != nil {
+		t.Fatalf("commentPullRequest() error: %v", err)
+	}
+	if _, err := os.Stat(log); !os.IsNotExist(err) {
+		t.Errorf("commentPullRequest() called gh on a dry run")
+	}
+}
+
+func TestPullRequestComment(t *testing.T) {
+	tests := []struct {
+		name    string
+		explain semver.Explanation
+		want    string
+	}{
+		{
+			name:    "initial version",
+			explain: semver.Explanation{},
+			want:    commentMarker + "\nThis pull request would produce the initial version **v0.1.0**.\n",
+		},
+		{
+			name: "bump",
+			explain: semver.Explanation{Previous: "v0.0.9", Bump: "minor", Reasons: []semver.BumpReason{
+				{Hash: "63ee8c4bd8d1", Subject: "feat: add", Bump: "minor"},
+			}},
+			want: commentMarker + "\nThis pull request would produce **v0.1.0**, a minor bump from v0.0.9.\n\nDeciding commits:\n\n* `63ee8c4` feat: add (minor)\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := pullRequestComment("v0.1.0", &test.explain); got != test.want {
+				t.Errorf("pullRequestComment() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLabelBump(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    []string
+		defaults bool
+		view     string
+		want     semver.BumpLevel
+	}{
+		{name: "no pull request", defaults: true, view: "fail", want: semver.BumpUndecided},
+		{name: "no matching label", defaults: true, view: "bug", want: semver.BumpUndecided},
+		{name: "semver label", defaults: true, view: "bug\nsemver:minor", want: semver.BumpMinor},
+		{name: "no bump label", defaults: true, view: "semver:none", want: semver.BumpNone},
+		{name: "highest label", rules: []string{"breaking=major"}, defaults: true, view: "semver:patch\nbreaking", want: semver.BumpMajor},
+		{name: "rules only", rules: []string{"breaking=major"}, view: "semver:minor", want: semver.BumpUndecided},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			captureLog(t)
+			log := stubGh(t, test.view)
+			t.Setenv("GITHUB_REF", "refs/pull/42/merge")
+			labels, err := parseLabels(test.rules, test.defaults)
+			if err != nil {
+				t.Fatalf("parseLabels() error: %v", err)
+			}
+			if got := labelBump(labels); got != test.want {
+				t.Errorf("labelBump() = %s, want %s", got, test.want)
+			}
+			// the labels of the pull request GitHub Actions checked out
+			want := "pr view 42 --json labels --jq .labels[].name"
+			if calls := ghCalls(t, log); len(calls) != 1 || calls[0] != want {
+				t.Errorf("labelBump() called gh %q, want %q", calls, want)
+			}
+		})
+	}
+}
+
+func TestParseLabelsError(t *testing.T) {
+	for _, rule := range []string{"breaking", "=major", "breaking=huge"} {
+		t.Run(rule, func(t *testing.T) {
+			if _, err := parseLabels([]string{rule}, false); err == nil {
+				t.Errorf("parseLabels() succeeded for rule %s", rule)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+const defaultRemote = "origin"
+
+// scpLikeURL matches remotes like git@github.com:org/repo.git
+var scpLikeURL = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
+
+// webURL returns the https URL of the repository behind a remote URL, for
+// both SSH and HTTPS remotes
+func webURL(remoteURL string) (string, error) {
+	var host, repoPath string
+	if strings.Contains(remoteURL, "://") {
+		parsed, err := url.Parse(remoteURL)
+		if err != nil {
+			return "", fmt.Errorf("couldn't parse remote url '%s': %w", remoteURL, err)
+		}
+		host, repoPath = parsed.Hostname(), parsed.Path
+	} else if matches := scpLikeURL.FindStringSubmatch(remoteURL); matches != nil {
+		host, repoPath = matches[1], matches[2]
+	} else {
+		return "", fmt.Errorf("unsupported remote url '%s'", remoteURL)
+	}
+
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	if host == "" || repoPath == "" {
+		return "", fmt.Errorf("unsupported remote url '%s'", remoteURL)
+	}
+	return fmt.Sprintf("https://%s/%s", host, repoPath), nil
+}
+
+// compareURL returns the URL comparing the previous and next tag
+func compareURL(repo *git.Repository, previousTag, nextTag string) (string, error) {
+	if previousTag == "" {
+		return "", fmt.Errorf("no previous tag to compare with")
+	}
+	remote, err := repo.Remote(defaultRemote)
+	if err != nil {
+		return "", fmt.Errorf("couldn't get remote '%s': %w", defaultRemote, err)
+	}
+	urls := remote.Config().URLs
+	if len(urls) == 0 {
+		return "", fmt.Errorf("remote '%s' has no url", defaultRemote)
+	}
+	repoURL, err := webURL(urls[0])
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/compare/%s...%s", repoURL, previousTag, nextTag), nil
+}
+
+// tokenAuth authenticates HTTPS remotes with the GitHub token from the
+// environment, other remotes use their default authentication
+func tokenAuth(repo *git.Repository, remoteName string) transport.AuthMethod {
+	if repo == nil || remoteName == "" {
+		return nil
+	}
+	remote, err := repo.Remote(remoteName)
+	if err != nil || len(remote.Config().URLs) == 0 || !strings.HasPrefix(remote.Config().URLs[0], "https://") {
+		return nil
+	}
+	for _, env := range []string{"GH_TOKEN", "GITHUB_TOKEN"} {
+		if token := os.Getenv(env); token != "" {
+			return &http.BasicAuth{Username: "x-access-token", Password: token}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// newWorktree returns an in-memory repository with a commit of README.md
+func newWorktree(t *testing.T) (*git.Repository, *git.Worktree) {
+	t.Helper()
+	// the GitHub environment of the test run would decide the branch
+	for _, name := range []string{"GITHUB_HEAD_REF", "GITHUB_REF_NAME", "GITHUB_REF_TYPE"} {
+		t.Setenv(name, "")
+	}
+	repo, err := git.Init(memory.NewStorage(), memfs.New())
+	if err != nil {
+		t.Fatalf("couldn't init repository: %v", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("couldn't get worktree: %v", err)
+	}
+	writeFile(t, worktree.Filesystem, "README.md", "readme")
+	commitAll(t, worktree, "chore: init")
+	return repo, worktree
+}
+
+// writeFile writes the content to the file of the filesystem
+func writeFile(t *testing.T, fs billy.Filesystem, name, content string) {
+	t.Helper()
+	file, err := fs.Create(name)
+	if err != nil {
+		t.Fatalf("couldn't create %s: %v", name, err)
+	}
+	defer file.Close()
+	if _, err := file.Write([]byte(content)); err != nil {
+		t.Fatalf("couldn't write %s: %v", name, err)
+	}
+}
+
+// commitAll commits all changes of the worktree
+func commitAll(t *testing.T, worktree *git.Worktree, message string) {
+	t.Helper()
+	if err := worktree.AddWithOptions(&git.AddOptions{All: true}); err != nil {
+		t.Fatalf("couldn't add changes: %v", err)
+	}
+	signature := &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()}
+	if _, err := worktree.Commit(message, &git.CommitOptions{Author: signature}); err != nil {
+		t.Fatalf("couldn't commit: %v", err)
+	}
+}
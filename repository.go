@@ -0,0 +1,67 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// openRepository opens the repository of GIT_DIR and GIT_WORK_TREE like git
+// does, the working directory defaults to both
+func openRepository() (*git.Repository, error) {
+	gitDir := os.Getenv("GIT_DIR")
+	if gitDir == "" {
+		repo, err := git.PlainOpenWithOptions(".", &git.PlainOpenOptions{DetectDotGit: true})
+		if errors.Is(err, git.ErrRepositoryNotExists) {
+			// a bare repository has no .git to detect
+			return git.PlainOpen(".")
+		}
+		return repo, err
+	}
+	gitDir, err := filepath.Abs(gitDir)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't resolve GIT_DIR: %w", err)
+	}
+	if _, err := os.Stat(gitDir); err != nil {
+		return nil, fmt.Errorf("couldn't open GIT_DIR: %w", err)
+	}
+	// without GIT_WORK_TREE, git takes the working directory
+	workTree, err := filepath.Abs(os.Getenv("GIT_WORK_TREE"))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't resolve GIT_WORK_TREE: %w", err)
+	}
+	storage := filesystem.NewStorage(osfs.New(gitDir), cache.NewObjectLRUDefault())
+	return git.Open(storage, osfs.New(workTree))
+}
+
+// repositoryRoot returns the worktree of the repository and its root, both
+// empty for a bare repository like a mirror
+func repositoryRoot(repo *git.Repository) (*git.Worktree, string, error) {
+	worktree, err := repo.Worktree()
+	if errors.Is(err, git.ErrIsBareRepository) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("couldn't get worktree: %w", err)
+	}
+	return worktree, worktree.Filesystem.Root(), nil
+}
@@ -0,0 +1,181 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/koozz/gh-semver/internal/semver"
+)
+
+// chdir changes the working directory for the test
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestRepositoryRootFromSubdirectory(t *testing.T) {
+	root := t.TempDir()
+	if _, err := git.PlainInit(root, false); err != nil {
+		t.Fatalf("couldn't init repository: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, defaultConfigFile), []byte("prefix: api\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	nested := filepath.Join(root, "services", "api")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, nested)
+	t.Setenv("GIT_DIR", "")
+
+	repo, err := openRepository()
+	if err != nil {
+		t.Fatalf("openRepository() error: %v", err)
+	}
+	worktree, gitRoot, err := repositoryRoot(repo)
+	if err != nil {
+		t.Fatalf("repositoryRoot() error: %v", err)
+	}
+	if worktree == nil {
+		t.Fatal("repositoryRoot() has no worktree")
+	}
+	want, _ := filepath.EvalSymlinks(root)
+	if got, _ := filepath.EvalSymlinks(gitRoot); got != want {
+		t.Errorf("repositoryRoot() = %s, want %s", got, want)
+	}
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	prefix := flags.String("prefix", "", "")
+	if err := applyConfig(flags, gitRoot, ""); err != nil {
+		t.Fatalf("applyConfig() error: %v", err)
+	}
+	if *prefix != "api" {
+		t.Errorf("applyConfig() prefix = %q, want api from the root config", *prefix)
+	}
+}
+
+func TestRepositoryRootOfBareRepository(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := git.PlainInit(dir, true); err != nil {
+		t.Fatalf("couldn't init repository: %v", err)
+	}
+	chdir(t, dir)
+	t.Setenv("GIT_DIR", "")
+
+	repo, err := openRepository()
+	if err != nil {
+		t.Fatalf("openRepository() error: %v", err)
+	}
+	worktree, gitRoot, err := repositoryRoot(repo)
+	if err != nil {
+		t.Fatalf("repositoryRoot() error: %v", err)
+	}
+	if worktree != nil || gitRoot != "" {
+		t.Errorf("repositoryRoot() = %v, %q, want no worktree or root", worktree, gitRoot)
+	}
+	if !isClean(worktree) {
+		t.Error("isClean() = false for a bare repository")
+	}
+}
+
+func TestOpenRepositoryFromGitDir(t *testing.T) {
+	root := t.TempDir()
+	repo, err := git.PlainInit(root, false)
+	if err != nil {
+		t.Fatalf("couldn't init repository: %v", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, worktree.Filesystem, "README.md", "readme")
+	commitAll(t, worktree, "feat: init")
+	if _, err := repo.CreateTag("v1.0.0", mustHead(t, repo), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		workTree string
+		wantRoot string
+	}{
+		{name: "working directory as worktree", wantRoot: "."},
+		{name: "worktree", workTree: root, wantRoot: root},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			elsewhere := t.TempDir()
+			chdir(t, elsewhere)
+			t.Setenv("GIT_DIR", filepath.Join(root, ".git"))
+			t.Setenv("GIT_WORK_TREE", test.workTree)
+			// the GitHub environment of the test run would decide the branch
+			for _, name := range []string{"GITHUB_HEAD_REF", "GITHUB_REF_NAME", "GITHUB_REF_TYPE"} {
+				t.Setenv(name, "")
+			}
+
+			opened, err := openRepository()
+			if err != nil {
+				t.Fatalf("openRepository() error: %v", err)
+			}
+			version, err := semver.NewConventionalCommits(opened, semver.Options{MainBranch: "master"}).SemVer()
+			if err != nil {
+				t.Fatalf("SemVer() error: %v", err)
+			}
+			if got := version.String(); got != "v1.0.0" {
+				t.Errorf("SemVer() = %s, want v1.0.0", got)
+			}
+			_, gitRoot, err := repositoryRoot(opened)
+			if err != nil {
+				t.Fatalf("repositoryRoot() error: %v", err)
+			}
+			want := test.wantRoot
+			if want == "." {
+				want = elsewhere
+			}
+			if gitRoot != want {
+				t.Errorf("repositoryRoot() = %s, want %s", gitRoot, want)
+			}
+		})
+	}
+}
+
+func TestOpenRepositoryFromMissingGitDir(t *testing.T) {
+	t.Setenv("GIT_DIR", filepath.Join(t.TempDir(), "missing"))
+	if _, err := openRepository(); err == nil {
+		t.Error("openRepository() succeeded with a missing GIT_DIR")
+	}
+}
+
+// mustHead returns the commit HEAD points at
+func mustHead(t *testing.T, repo *git.Repository) plumbing.Hash {
+	t.Helper()
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatalf("couldn't get head: %v", err)
+	}
+	return head.Hash()
+}
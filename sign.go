@@ -0,0 +1,127 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+const passphraseEnv = "GPG_PASSPHRASE"
+
+// loadSigningKey reads the first private key from an armored key file
+func loadSigningKey(path string) (*openpgp.Entity, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open signing key: %w", err)
+	}
+	defer file.Close()
+
+	entities, err := openpgp.ReadArmoredKeyRing(file)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read signing key: %w", err)
+	}
+	for _, entity := range entities {
+		if entity.PrivateKey == nil {
+			continue
+		}
+		if entity.PrivateKey.Encrypted {
+			if err := decryptSigningKey(entity); err != nil {
+				return nil, err
+			}
+		}
+		return entity, nil
+	}
+	return nil, fmt.Errorf("no private key found in %s", path)
+}
+
+// decryptSigningKey decrypts the private keys with the passphrase from the
+// environment or, when not set, from gpg-agent
+func decryptSigningKey(entity *openpgp.Entity) error {
+	keyID := entity.PrimaryKey.KeyIdString()
+	passphrase := os.Getenv(passphraseEnv)
+	if passphrase == "" {
+		var err error
+		if passphrase, err = agentPassphrase(keyID); err != nil {
+			return fmt.Errorf("signing key %s is encrypted, set %s or use gpg-agent: %w", keyID, passphraseEnv, err)
+		}
+	}
+	if err := entity.DecryptPrivateKeys([]byte(passphrase)); err != nil {
+		return fmt.Errorf("couldn't decrypt signing key %s: %w", keyID, err)
+	}
+	return nil
+}
+
+// agentPassphrase asks gpg-agent for the passphrase, which is cached by the
+// agent for subsequent runs
+func agentPassphrase(keyID string) (string, error) {
+	request := fmt.Sprintf("GET_PASSPHRASE gh-semver:%s X Passphrase Passphrase+for+signing+key+%s", keyID, keyID)
+	var stdOut bytes.Buffer
+	cmd := exec.Command("gpg-connect-agent", request, "/bye")
+	cmd.Stdout = &stdOut
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(stdOut.String(), "\n") {
+		if strings.HasPrefix(line, "ERR ") {
+			return "", fmt.Errorf("gpg-agent: %s", strings.TrimPrefix(line, "ERR "))
+		}
+		if strings.HasPrefix(line, "OK ") {
+			passphrase, err := hex.DecodeString(strings.TrimPrefix(line, "OK "))
+			if err != nil {
+				return "", fmt.Errorf("couldn't decode gpg-agent response: %w", err)
+			}
+			return string(passphrase), nil
+		}
+	}
+	return "", fmt.Errorf("no passphrase received from gpg-agent")
+}
+
+// signingTagger returns the tagger of a signed tag, which has to be an
+// identity of the key for GitHub to show the tag as verified. The tagger is
+// read from the git config like git does, or taken from the key when unset.
+func signingTagger(repo *git.Repository, signer *openpgp.Entity) (*object.Signature, error) {
+	cfg, err := repo.ConfigScoped(config.SystemScope)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read git config: %w", err)
+	}
+	name, email := cfg.User.Name, cfg.User.Email
+	if cfg.Author.Name != "" && cfg.Author.Email != "" {
+		name, email = cfg.Author.Name, cfg.Author.Email
+	}
+	if email == "" {
+		identity := signer.PrimaryIdentity()
+		if identity == nil || identity.UserId.Email == "" {
+			return nil, fmt.Errorf("no tagger email in the git config or signing key %s", signer.PrimaryKey.KeyIdString())
+		}
+		return &object.Signature{Name: identity.UserId.Name, Email: identity.UserId.Email, When: time.Now()}, nil
+	}
+	for _, identity := range signer.Identities {
+		if strings.EqualFold(identity.UserId.Email, email) {
+			return &object.Signature{Name: name, Email: email, When: time.Now()}, nil
+		}
+	}
+	return nil, fmt.Errorf("tagger email %s isn't an identity of signing key %s, the tag wouldn't be verified", email, signer.PrimaryKey.KeyIdString())
+}
@@ -0,0 +1,178 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+// writeSigningKey writes a new armored private key, encrypted with the
+// passphrase unless empty, and returns its path
+func writeSigningKey(t *testing.T, passphrase string) string {
+	t.Helper()
+	entity, err := openpgp.NewEntity("Test", "", "test@example.com", nil)
+	if err != nil {
+		t.Fatalf("couldn't generate signing key: %v", err)
+	}
+	if passphrase != "" {
+		if err := entity.EncryptPrivateKeys([]byte(passphrase), nil); err != nil {
+			t.Fatalf("couldn't encrypt signing key: %v", err)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "key.asc")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	writer, err := armor.Encode(file, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.SerializePrivateWithoutSigning(writer, nil); err != nil {
+		t.Fatalf("couldn't write signing key: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadSigningKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+		env        string
+		wantErr    bool
+	}{
+		{name: "unencrypted"},
+		{name: "passphrase from the environment", passphrase: "secret", env: "secret"},
+		{name: "wrong passphrase", passphrase: "secret", env: "guess", wantErr: true},
+		{name: "no passphrase nor agent", passphrase: "secret", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(passphraseEnv, test.env)
+			// without gpg-connect-agent on the path the agent can't be asked
+			t.Setenv("PATH", t.TempDir())
+			path := writeSigningKey(t, test.passphrase)
+			entity, err := loadSigningKey(path)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("loadSigningKey() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadSigningKey() error: %v", err)
+			}
+			if entity.PrivateKey.Encrypted {
+				t.Error("loadSigningKey() returned an encrypted key")
+			}
+		})
+	}
+}
+
+// testSigner returns the entity of a new unencrypted signing key, with the
+// git config of the user out of the way
+func testSigner(t *testing.T) *openpgp.Entity {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	signer, err := loadSigningKey(writeSigningKey(t, ""))
+	if err != nil {
+		t.Fatalf("loadSigningKey() error: %v", err)
+	}
+	return signer
+}
+
+func TestSigningTagger(t *testing.T) {
+	tests := []struct {
+		name      string
+		userName  string
+		userEmail string
+		want      string
+		wantErr   bool
+	}{
+		{name: "identity of the key", want: "Test <test@example.com>"},
+		{name: "git config", userName: "Release Bot", userEmail: "TEST@example.com", want: "Release Bot <TEST@example.com>"},
+		{name: "email of another key", userName: "Release Bot", userEmail: "bot@example.com", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			signer := testSigner(t)
+			repo, _ := newWorktree(t)
+			cfg, err := repo.Config()
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg.User.Name, cfg.User.Email = test.userName, test.userEmail
+			if err := repo.SetConfig(cfg); err != nil {
+				t.Fatal(err)
+			}
+			tagger, err := signingTagger(repo, signer)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("signingTagger() error = %v, want error %t", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := tagger.Name + " <" + tagger.Email + ">"; got != test.want {
+				t.Errorf("signingTagger() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGitTagSigned(t *testing.T) {
+	captureLog(t)
+	signer := testSigner(t)
+	repo, target := twoCommits(t)
+	gitTag(repo, target, "v1.0.0", annotatedTag, signer, false)
+
+	ref, err := repo.Tag("v1.0.0")
+	if err != nil {
+		t.Fatalf("couldn't find tag: %v", err)
+	}
+	tagObject, err := repo.TagObject(ref.Hash())
+	if err != nil {
+		t.Fatalf("gitTag() didn't create an annotated tag: %v", err)
+	}
+	if !strings.HasPrefix(tagObject.PGPSignature, "-----BEGIN PGP SIGNATURE-----") {
+		t.Errorf("gitTag() signature = %q, want a PGP signature block", tagObject.PGPSignature)
+	}
+	// the tag is verified when the tagger is an identity of the key
+	if got := tagObject.Tagger.Email; got != "test@example.com" {
+		t.Errorf("gitTag() tagger = %s, want the email of the key", got)
+	}
+	var publicKey bytes.Buffer
+	writer, err := armor.Encode(&publicKey, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := signer.Serialize(writer); err != nil {
+		t.Fatal(err)
+	}
+	writer.Close()
+	if _, err := tagObject.Verify(publicKey.String()); err != nil {
+		t.Errorf("couldn't verify the signature of the tag: %v", err)
+	}
+}
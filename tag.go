@@ -0,0 +1,190 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/koozz/gh-semver/internal/semver"
+)
+
+const (
+	annotatedTag   = "annotated"
+	lightweightTag = "lightweight"
+)
+
+// checkTagBranch fails unless the branch of HEAD matches one of the patterns,
+// by default only the main branch
+func checkTagBranch(conventionalCommits *semver.ConventionalCommits, tagBranches []string) error {
+	headBranch, err := conventionalCommits.HeadBranch()
+	if err != nil {
+		return err
+	}
+	if len(tagBranches) == 0 {
+		mainBranch, err := conventionalCommits.MainBranch()
+		if err != nil {
+			return err
+		}
+		tagBranches = []string{mainBranch}
+	}
+	for _, pattern := range tagBranches {
+		if matched, _ := path.Match(pattern, headBranch); matched {
+			return nil
+		}
+	}
+	return fmt.Errorf("refusing to tag on branch '%s', allowed: %s", headBranch, strings.Join(tagBranches, ", "))
+}
+
+// checkReachable makes sure the commit to tag is HEAD or one of its ancestors
+func checkReachable(repo *git.Repository, target plumbing.Hash) {
+	headRef, err := repo.Head()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: couldn't get head: %v\n", err)
+		os.Exit(1)
+	}
+	if headRef.Hash() == target {
+		return
+	}
+	targetCommit, err := repo.CommitObject(target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: couldn't get commit %s: %v\n", target, err)
+		os.Exit(1)
+	}
+	headCommit, err := repo.CommitObject(headRef.Hash())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: couldn't get commit %s: %v\n", headRef.Hash(), err)
+		os.Exit(1)
+	}
+	reachable, err := targetCommit.IsAncestor(headCommit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: couldn't determine whether %s is reachable: %v\n", target, err)
+		os.Exit(1)
+	}
+	if !reachable {
+		fmt.Fprintf(os.Stderr, "error: commit %s isn't reachable from HEAD\n", target)
+		os.Exit(1)
+	}
+}
+
+// gitTag creates the tag at the target commit unless it exists and returns the
+// type of tag created
+func gitTag(repo *git.Repository, target plumbing.Hash, tagVersion, tagType string, signer *openpgp.Entity, dryRun bool) string {
+	if _, err := repo.Tag(tagVersion); err == nil {
+		logger.Printf("info: tag %s already exists", tagVersion)
+		return "existing"
+	}
+
+	if dryRun {
+		planLogger.Printf("info: would create %s tag %s at %s", tagType, tagVersion, target)
+		return tagType
+	}
+	var opts *git.CreateTagOptions
+	if tagType == annotatedTag {
+		opts = &git.CreateTagOptions{
+			Message: tagVersion,
+			SignKey: signer,
+		}
+		if signer != nil {
+			tagger, err := signingTagger(repo, signer)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				os.Exit(1)
+			}
+			opts.Tagger = tagger
+		}
+	}
+	ref, err := repo.CreateTag(tagVersion, target, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error creating tag: %v\n", err)
+		os.Exit(1)
+	}
+	if signer != nil {
+		// the signature has to be on the tag object before it is pushed
+		if tagObject, err := repo.TagObject(ref.Hash()); err != nil || tagObject.PGPSignature == "" {
+			fmt.Fprintf(os.Stderr, "error: tag %s was created without signature\n", tagVersion)
+			os.Exit(1)
+		}
+	}
+	logger.Printf("info: created %s tag %s", tagType, tagVersion)
+	return tagType
+}
+
+// floatingTags returns the major and minor tags that follow the releases, like
+// v1 and v1.2
+func floatingTags(version *semver.SemVer) []string {
+	names := []string{
+		fmt.Sprintf("%s%d", version.LeadingV, version.Major),
+		fmt.Sprintf("%s%d.%d", version.LeadingV, version.Major, version.Minor),
+	}
+	for i, name := range names {
+		names[i] = version.TagPrefix() + name
+	}
+	return names
+}
+
+// moveFloatingTag points the lightweight floating tag at the target commit
+func moveFloatingTag(repo *git.Repository, target plumbing.Hash, name string, dryRun bool) {
+	if dryRun {
+		planLogger.Printf("info: would move floating tag %s to %s", name, target)
+		return
+	}
+	if _, err := repo.Tag(name); err == nil {
+		if err := repo.DeleteTag(name); err != nil {
+			fmt.Fprintf(os.Stderr, "error: couldn't delete floating tag %s: %v\n", name, err)
+			os.Exit(1)
+		}
+	}
+	if _, err := repo.CreateTag(name, target, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "error: couldn't create floating tag %s: %v\n", name, err)
+		os.Exit(1)
+	}
+	logger.Printf("info: moved floating tag %s to %s", name, target)
+}
+
+// pushTag pushes the tag of the target commit to the default remote, force
+// replaces the tag on the remote
+func pushTag(repo *git.Repository, tagVersion string, target plumbing.Hash, force, dryRun bool) {
+	remote, err := repo.Remote(defaultRemote)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: couldn't get remote '%s': %v\n", defaultRemote, err)
+		os.Exit(1)
+	}
+	refSpec := config.RefSpec(fmt.Sprintf("refs/tags/%[1]s:refs/tags/%[1]s", tagVersion))
+	if force {
+		refSpec = "+" + refSpec
+	}
+
+	if dryRun {
+		planLogger.Printf("info: would push %s targeting %s to %s (%s)", refSpec, target, defaultRemote, strings.Join(remote.Config().URLs, ", "))
+		return
+	}
+
+	err = repo.Push(&git.PushOptions{
+		RemoteName: defaultRemote,
+		RefSpecs:   []config.RefSpec{refSpec},
+		Auth:       tokenAuth(repo, defaultRemote),
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		fmt.Fprintf(os.Stderr, "error pushing tag: %v\n", err)
+		os.Exit(1)
+	}
+	logger.Printf("info: pushed %s to %s", refSpec, defaultRemote)
+}
@@ -0,0 +1,197 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/koozz/gh-semver/internal/semver"
+)
+
+// captureLog collects the output of the loggers during the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	writer, planWriter := logger.Writer(), planLogger.Writer()
+	logger.SetOutput(&buf)
+	planLogger.SetOutput(&buf)
+	t.Cleanup(func() {
+		logger.SetOutput(writer)
+		planLogger.SetOutput(planWriter)
+	})
+	return &buf
+}
+
+// twoCommits returns a repository with two commits, the first one to tag
+// instead of HEAD
+func twoCommits(t *testing.T) (*git.Repository, plumbing.Hash) {
+	t.Helper()
+	repo, worktree := newWorktree(t)
+	first, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, worktree.Filesystem, "CHANGELOG.md", "changes")
+	commitAll(t, worktree, "fix: second")
+	return repo, first.Hash()
+}
+
+func TestFloatingTags(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want []string
+	}{
+		{tag: "v1.2.3", want: []string{"v1", "v1.2"}},
+		{tag: "1.2.3", want: []string{"1", "1.2"}},
+		{tag: "api-v1.2.3", want: []string{"api-v1", "api-v1.2"}},
+		{tag: "api/v1.2.3", want: []string{"api/v1", "api/v1.2"}},
+	}
+	for _, test := range tests {
+		t.Run(test.tag, func(t *testing.T) {
+			version, err := semver.ParseSemVer(test.tag)
+			if err != nil {
+				t.Fatalf("ParseSemVer() error: %v", err)
+			}
+			if got := floatingTags(version); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("floatingTags() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMoveFloatingTag(t *testing.T) {
+	captureLog(t)
+	repo, first := twoCommits(t)
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the floating tags of the previous release move, new ones are created
+	if _, err := repo.CreateTag("v1", first, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"v1", "v1.3"} {
+		moveFloatingTag(repo, head.Hash(), name, false)
+		ref, err := repo.Tag(name)
+		if err != nil {
+			t.Fatalf("couldn't find floating tag %s: %v", name, err)
+		}
+		if ref.Hash() != head.Hash() {
+			t.Errorf("moveFloatingTag() pointed %s at %s, want %s", name, ref.Hash(), head.Hash())
+		}
+	}
+}
+
+func TestGitTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		tagType string
+	}{
+		{name: "lightweight", tagType: lightweightTag},
+		{name: "annotated", tagType: annotatedTag},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			log := captureLog(t)
+			repo, target := twoCommits(t)
+			if got := gitTag(repo, target, "v1.0.0", test.tagType, nil, false); got != test.tagType {
+				t.Errorf("gitTag() = %s, want %s", got, test.tagType)
+			}
+			if want := "created " + test.tagType + " tag v1.0.0"; !strings.Contains(log.String(), want) {
+				t.Errorf("gitTag() logged %q, want %q", log.String(), want)
+			}
+			ref, err := repo.Tag("v1.0.0")
+			if err != nil {
+				t.Fatalf("couldn't find tag: %v", err)
+			}
+			tagged := ref.Hash()
+			tagObject, err := repo.TagObject(ref.Hash())
+			if err == nil {
+				tagged = tagObject.Target
+			}
+			// the reported type is the type of the tag created
+			if annotated := err == nil; annotated != (test.tagType == annotatedTag) {
+				t.Errorf("gitTag() created an annotated tag %t for type %s", annotated, test.tagType)
+			}
+			if tagged != target {
+				t.Errorf("gitTag() tagged %s, want %s", tagged, target)
+			}
+			if got := gitTag(repo, target, "v1.0.0", test.tagType, nil, false); got != "existing" {
+				t.Errorf("gitTag() = %s for an existing tag, want existing", got)
+			}
+		})
+	}
+}
+
+func TestGitTagDryRun(t *testing.T) {
+	log := captureLog(t)
+	repo, target := twoCommits(t)
+	gitTag(repo, target, "v1.0.0", annotatedTag, nil, true)
+	if _, err := repo.Tag("v1.0.0"); err == nil {
+		t.Error("gitTag() created a tag on a dry run")
+	}
+	if want := "would create annotated tag v1.0.0 at " + target.String(); !strings.Contains(log.String(), want) {
+		t.Errorf("gitTag() logged %q, want %q", log.String(), want)
+	}
+}
+
+func TestPushTagDryRun(t *testing.T) {
+	log := captureLog(t)
+	repo, target := twoCommits(t)
+	// a push to the unroutable remote would fail
+	_, err := repo.CreateRemote(&config.RemoteConfig{Name: defaultRemote, URLs: []string{"https://example.invalid/repo.git"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pushTag(repo, "v1", target, true, true)
+	want := "would push +refs/tags/v1:refs/tags/v1 targeting " + target.String() + " to origin (https://example.invalid/repo.git)"
+	if !strings.Contains(log.String(), want) {
+		t.Errorf("pushTag() logged %q, want %q", log.String(), want)
+	}
+}
+
+func TestCheckTagBranch(t *testing.T) {
+	tests := []struct {
+		branch   string
+		patterns []string
+		wantErr  bool
+	}{
+		{branch: "master"},
+		{branch: "feature/login", wantErr: true},
+		{branch: "release/1.x", patterns: []string{"release/*"}},
+		{branch: "master", patterns: []string{"release/*"}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.branch, func(t *testing.T) {
+			repo, worktree := newWorktree(t)
+			if test.branch != "master" {
+				err := worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName(test.branch), Create: true})
+				if err != nil {
+					t.Fatalf("couldn't checkout %s: %v", test.branch, err)
+				}
+			}
+			conventionalCommits := semver.NewConventionalCommits(repo, semver.Options{MainBranch: "master"})
+			err := checkTagBranch(conventionalCommits, test.patterns)
+			if (err != nil) != test.wantErr {
+				t.Errorf("checkTagBranch() error = %v, want error %t", err, test.wantErr)
+			}
+		})
+	}
+}
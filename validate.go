@@ -0,0 +1,53 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"fmt"
+
+	"github.com/koozz/gh-semver/internal/semver"
+)
+
+// validateVersion checks that a proposed version is the version the commits
+// call for, parsing the versions like the tags
+func validateVersion(explain *semver.Explanation, computed *semver.SemVer, proposed string, opts semver.ParseOptions) error {
+	if explain.Previous == "" {
+		// any version will do as the initial version
+		return nil
+	}
+	proposedVersion, err := semver.ParseSemVerWithOptions(proposed, opts)
+	if err != nil {
+		return fmt.Errorf("couldn't parse proposed version: %w", err)
+	}
+	if proposedVersion.Core() == computed.Core() {
+		return nil
+	}
+	previousVersion, err := semver.ParseSemVerWithOptions(explain.Previous, opts)
+	if err != nil {
+		return fmt.Errorf("couldn't parse previous version: %w", err)
+	}
+	bump := proposedVersion.BumpLevelFrom(previousVersion)
+	return fmt.Errorf("version %s is a %s bump from %s, the commits call for the %s bump to %s", proposed, bump, explain.Previous, explain.Bump, computed.Core())
+}
+
+// validateStrict checks that the version, without prefix and leading v, is
+// valid SemVer 2.0.0
+func validateStrict(version *semver.SemVer, release bool) error {
+	bare := *version
+	bare.Prefix, bare.LeadingV = "", ""
+	if output := bare.PrintTag(release); !semver.IsStrict(output) {
+		return fmt.Errorf("version %s isn't valid SemVer 2.0.0", output)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+// Copyright 2022 Jan van den Berg
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/koozz/gh-semver/internal/semver"
+)
+
+func TestValidateStrict(t *testing.T) {
+	tests := []struct {
+		name    string
+		version func() *semver.SemVer
+		release bool
+		wantErr bool
+	}{
+		{
+			name:    "release with prefix",
+			version: func() *semver.SemVer { v := semver.NewSemVer(1, 2, 3); v.Prefix = "api/"; return v },
+		},
+		{
+			name: "sanitized branch",
+			version: func() *semver.SemVer {
+				v := semver.NewSemVer(1, 2, 3)
+				v.SetBranch("feature/under_score")
+				v.SetCommitDistance(2)
+				v.SetCommitHash("0123456789abcdef")
+				return v
+			},
+		},
+		{
+			name: "numeric hash",
+			version: func() *semver.SemVer {
+				v := semver.NewSemVer(1, 2, 3)
+				v.SetBranch("main")
+				v.SetCommitDistance(2)
+				v.SetCommitHash("0123456789")
+				return v
+			},
+		},
+		{
+			name:    "invalid prerelease",
+			version: func() *semver.SemVer { v := semver.NewSemVer(1, 2, 3); v.Prerelease = "rc_1"; return v },
+			wantErr: true,
+		},
+		{
+			name:    "leading zero prerelease",
+			version: func() *semver.SemVer { v := semver.NewSemVer(1, 2, 3); v.Prerelease = "rc.01"; return v },
+			wantErr: true,
+		},
+		{
+			name:    "invalid prerelease of a release",
+			version: func() *semver.SemVer { v := semver.NewSemVer(1, 2, 3); v.Prerelease = "rc_1"; return v },
+			release: true,
+		},
+		{
+			name:    "empty metadata identifier",
+			version: func() *semver.SemVer { v := semver.NewSemVer(1, 2, 3); v.Metadata = "a..b"; return v },
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateStrict(test.version(), test.release)
+			if (err != nil) != test.wantErr {
+				t.Errorf("validateStrict() error = %v, want error %t", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous string
+		computed *semver.SemVer
+		proposed string
+		opts     semver.ParseOptions
+		wantErr  bool
+	}{
+		{name: "initial", computed: semver.NewSemVer(0, 1, 0), proposed: "v1.0.0"},
+		{name: "computed", previous: "v1.2.3", computed: semver.NewSemVer(1, 3, 0), proposed: "v1.3.0"},
+		{name: "computed without v", previous: "v1.2.3", computed: semver.NewSemVer(1, 3, 0), proposed: "1.3.0"},
+		{name: "same bump further", previous: "v1.2.3", computed: semver.NewSemVer(1, 3, 0), proposed: "v1.5.0", wantErr: true},
+		{name: "smaller bump", previous: "v1.2.3", computed: semver.NewSemVer(1, 3, 0), proposed: "v1.2.4", wantErr: true},
+		{name: "larger bump", previous: "v1.2.3", computed: semver.NewSemVer(1, 3, 0), proposed: "v2.0.0", wantErr: true},
+		{name: "decrease", previous: "v1.2.3", computed: semver.NewSemVer(1, 3, 0), proposed: "v1.0.0", wantErr: true},
+		{
+			name:     "tag pattern",
+			previous: "release-1.2.3",
+			computed: semver.NewSemVer(1, 3, 0),
+			proposed: "release-1.3.0",
+			opts:     semver.ParseOptions{Pattern: regexp.MustCompile(`release-(?P<major>\d+)\.(?P<minor>\d+)\.(?P<patch>\d+)`)},
+		},
+		{
+			name:     "tag pattern further",
+			previous: "release-1.2.3",
+			computed: semver.NewSemVer(1, 3, 0),
+			proposed: "release-1.4.0",
+			opts:     semver.ParseOptions{Pattern: regexp.MustCompile(`release-(?P<major>\d+)\.(?P<minor>\d+)\.(?P<patch>\d+)`)},
+			wantErr:  true,
+		},
+		{name: "invalid", previous: "v1.2.3", computed: semver.NewSemVer(1, 3, 0), proposed: "latest", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			explain := &semver.Explanation{Previous: test.previous, Bump: "minor"}
+			err := validateVersion(explain, test.computed, test.proposed, test.opts)
+			if (err != nil) != test.wantErr {
+				t.Errorf("validateVersion() error = %v, want error %t", err, test.wantErr)
+			}
+		})
+	}
+}